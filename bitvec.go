@@ -0,0 +1,108 @@
+/*
+ * Copyright 2020 Rock Lei Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sa
+
+import (
+	"math/bits"
+	"sort"
+)
+
+// number of words per block of rank directory
+const rankBlock = 8
+
+// bitvec bit vector with rank directory
+type bitvec struct {
+	bits []uint64
+
+	// ranks[b] number of ones before block b
+	ranks []int
+}
+
+func newBitvec(n int) *bitvec {
+	return &bitvec{bits: make([]uint64, (n+63)>>6)}
+}
+
+func (b *bitvec) set(i int) {
+	b.bits[i>>6] |= 1 << uint(i&63)
+}
+
+func (b *bitvec) get(i int) bool {
+	return b.bits[i>>6]&(1<<uint(i&63)) != 0
+}
+
+// build rank directory, must be called after all bits are set
+func (b *bitvec) build() {
+	b.ranks = make([]int, len(b.bits)/rankBlock+1)
+	for w, sum := 0, 0; w < len(b.bits); w++ {
+		sum += bits.OnesCount64(b.bits[w])
+		if (w+1)%rankBlock == 0 {
+			b.ranks[(w+1)/rankBlock] = sum
+		}
+	}
+}
+
+// rank1 returns number of ones before i
+func (b *bitvec) rank1(i int) int {
+	w := i >> 6
+	r := b.ranks[w/rankBlock]
+	for _, x := range b.bits[w/rankBlock*rankBlock : w] {
+		r += bits.OnesCount64(x)
+	}
+	if i&63 > 0 {
+		r += bits.OnesCount64(b.bits[w] & (1<<uint(i&63) - 1))
+	}
+	return r
+}
+
+// rank0 returns number of zeros before i
+func (b *bitvec) rank0(i int) int {
+	return i - b.rank1(i)
+}
+
+// select1 returns the position of the k-th one, k starts from 0
+func (b *bitvec) select1(k int) int {
+	blk := sort.Search(len(b.ranks), func(i int) bool { return b.ranks[i] > k }) - 1
+	k -= b.ranks[blk]
+	for w := blk * rankBlock; ; w++ {
+		if n := bits.OnesCount64(b.bits[w]); k >= n {
+			k -= n
+		} else {
+			return w<<6 + selectWord(b.bits[w], k)
+		}
+	}
+}
+
+// select0 returns the position of the k-th zero, k starts from 0
+func (b *bitvec) select0(k int) int {
+	blk := sort.Search(len(b.ranks), func(i int) bool { return i*rankBlock*64-b.ranks[i] > k }) - 1
+	k -= blk*rankBlock*64 - b.ranks[blk]
+	for w := blk * rankBlock; ; w++ {
+		if n := 64 - bits.OnesCount64(b.bits[w]); k >= n {
+			k -= n
+		} else {
+			return w<<6 + selectWord(^b.bits[w], k)
+		}
+	}
+}
+
+// selectWord returns the position of the k-th one in x
+func selectWord(x uint64, k int) int {
+	for ; k > 0; k-- {
+		x &= x - 1
+	}
+	return bits.TrailingZeros64(x)
+}
@@ -0,0 +1,269 @@
+/*
+ * Copyright 2020 Rock Lei Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Command sa builds and inspects BWT and suffix array files.
+//
+// Usage:
+//
+//	sa bwt [-lines] input [output]      BWT and Aux of input, see sa.Index
+//	sa unbwt [-lines] input [output]    restore text from the output of bwt
+//	sa sa [-lines] [-width 8] input [output]
+//	                                    suffix array as little endian integers
+//	sa stats [-lines] input             length, documents, alphabet, BWT runs and entropy
+//
+// Input and output "-" are stdin and stdout, output is stdout if omitted. With -lines, every
+// line of input is a document divided by separator, unbwt writes documents as lines. unbwt restores
+// an index of sa.Options by the mode saved in its Aux, binary or separator indexes are written as they are.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"time"
+
+	"github.com/rleiwang/sa"
+)
+
+const usage = `usage: sa <command> [flags] input [output]
+
+commands:
+  bwt     BWT and Aux of input
+  unbwt   restore text from the output of bwt
+  sa      suffix array as little endian integers
+  stats   length, documents, alphabet, BWT runs and entropy of input
+
+run "sa <command> -h" for flags of command
+`
+
+var errUsage = errors.New(usage)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout); err == flag.ErrHelp {
+		os.Exit(0)
+	} else if err != nil {
+		fmt.Fprint(os.Stderr, err)
+		if err != errUsage {
+			fmt.Fprintln(os.Stderr)
+		}
+		os.Exit(2)
+	}
+}
+
+// run executes command of args, stdin and stdout are used for "-"
+func run(args []string, stdin io.Reader, stdout io.Writer) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	switch args[0] {
+	case "bwt", "unbwt", "sa", "stats":
+	default:
+		return errUsage
+	}
+
+	fs := flag.NewFlagSet("sa "+args[0], flag.ContinueOnError)
+	lines := fs.Bool("lines", false, "every line of input is a document divided by separator")
+	width := 8
+	if args[0] == "sa" {
+		fs.IntVar(&width, "width", 8, "bytes of each integer, 4 or 8")
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() < 1 || fs.NArg() > 2 || (args[0] == "stats" && fs.NArg() > 1) {
+		return fmt.Errorf("usage: sa %s [flags] input [output]", args[0])
+	}
+
+	in, err := readInput(fs.Arg(0), stdin)
+	if err != nil {
+		return err
+	}
+
+	out, err := createOutput(fs.Arg(1), stdout)
+	if err != nil {
+		return err
+	}
+	switch args[0] {
+	case "bwt":
+		err = bwt(in, *lines, out)
+	case "unbwt":
+		err = unbwt(in, *lines, out)
+	case "sa":
+		err = suffixArray(in, *lines, width, out)
+	case "stats":
+		err = stats(in, *lines, out)
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
+// text returns input as text of BWT, lines are converted to documents divided by separator in place,
+// as sa.Collection.AddLines does
+func text(in []byte, lines bool) ([]byte, error) {
+	if lines && len(in) > 0 && in[len(in)-1] == '\n' {
+		in = in[:len(in)-1]
+	}
+	if bytes.IndexByte(in, 0) >= 0 || (lines && bytes.IndexByte(in, 1) >= 0) {
+		return nil, sa.ErrReservedByte
+	}
+	if lines {
+		for i, c := range in {
+			if c == '\n' {
+				in[i] = 1
+			}
+		}
+	}
+
+	return in, nil
+}
+
+// inPlace returns t with one byte of spare capacity for BWT in place, t is copied only if it is full
+func inPlace(t []byte) []byte {
+	if cap(t) > len(t) {
+		return t
+	}
+	return append(t, 0)[:len(t)]
+}
+
+func bwt(in []byte, lines bool, out io.Writer) error {
+	t, err := text(in, lines)
+	if err != nil {
+		return err
+	} else if len(t) == 0 {
+		return errors.New("sa: empty input")
+	}
+
+	// t is not used afterwards
+	l, b, aux := (&sa.Options{InPlace: true}).BWT(inPlace(t))
+	_, err = (&sa.Index{L: l, BWT: b, Aux: aux}).WriteTo(out)
+	return err
+}
+
+func unbwt(in []byte, lines bool, out io.Writer) error {
+	var x sa.Index
+	if _, err := x.ReadFrom(bytes.NewReader(in)); err != nil {
+		return err
+	}
+
+	// note: lines are divided by (1) unless the index is binary or of another separator
+	t := x.Text()
+	if lines && !x.Aux.Binary && x.Aux.Separator == 0 {
+		for i, c := range t {
+			if c == 1 {
+				t[i] = '\n'
+			}
+		}
+		t = append(t, '\n')
+	}
+
+	_, err := out.Write(t)
+	return err
+}
+
+func suffixArray(in []byte, lines bool, width int, out io.Writer) error {
+	if width != 4 && width != 8 {
+		return fmt.Errorf("sa: width must be 4 or 8, not %d", width)
+	}
+	t, err := text(in, lines)
+	if err != nil {
+		return err
+	} else if width == 4 && len(t) > math.MaxInt32 {
+		return fmt.Errorf("sa: input of %d bytes does not fit width 4", len(t))
+	}
+
+	bw := bufio.NewWriter(out)
+	var b [8]byte
+	for _, p := range sa.SuffixArray(t) {
+		binary.LittleEndian.PutUint64(b[:], uint64(p))
+		bw.Write(b[:width])
+	}
+
+	return bw.Flush()
+}
+
+func stats(in []byte, lines bool, out io.Writer) error {
+	t, err := text(in, lines)
+	if err != nil {
+		return err
+	} else if len(t) == 0 {
+		return errors.New("sa: empty input")
+	}
+
+	var freqs [256]int
+	docs := 1
+	for _, c := range t {
+		freqs[c]++
+		if c == 1 {
+			docs++
+		}
+	}
+	alphabet, entropy := 0, 0.0
+	for _, f := range freqs {
+		if f > 0 {
+			alphabet++
+			p := float64(f) / float64(len(t))
+			entropy -= p * math.Log2(p)
+		}
+	}
+
+	n, t := len(t), inPlace(t)
+	start := time.Now()
+	_, b, _ := (&sa.Options{InPlace: true}).BWT(t)
+	elapsed := time.Since(start)
+	runs := sa.NewRLBWT(b).Runs()
+
+	fmt.Fprintf(out, "length     %d\n", n)
+	fmt.Fprintf(out, "documents  %d\n", docs)
+	fmt.Fprintf(out, "alphabet   %d\n", alphabet)
+	fmt.Fprintf(out, "runs       %d (%.2f bytes per run)\n", runs, float64(n)/float64(runs))
+	fmt.Fprintf(out, "entropy    %.3f bits per byte\n", entropy)
+	fmt.Fprintf(out, "bwt time   %v\n", elapsed)
+
+	return nil
+}
+
+func readInput(path string, stdin io.Reader) ([]byte, error) {
+	if path == "-" {
+		return ioutil.ReadAll(stdin)
+	}
+	return ioutil.ReadFile(path)
+}
+
+// createOutput creates file of path, or stdout if path is empty or "-"
+func createOutput(path string, stdout io.Writer) (io.WriteCloser, error) {
+	if path == "" || path == "-" {
+		return nopCloser{stdout}, nil
+	}
+	return os.Create(path)
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rleiwang/sa"
+)
+
+func TestRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	input := "sisisim\nsisisim\n\nanana\n"
+	path, index := filepath.Join(dir, "input"), filepath.Join(dir, "index")
+	if err := ioutil.WriteFile(path, []byte(input), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := run([]string{"bwt", "-lines", path, index}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := run([]string{"unbwt", "-lines", index}, nil, &out); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), input; got != want {
+		t.Errorf("unbwt = %q, want %q", got, want)
+	}
+
+	// binary index restores bytes (0) and (1) as they are
+	bin := []byte{0, 1, 5, 0, 1, 1, 7, 0}
+	l, b, aux := (&sa.Options{Binary: true}).BWT(bin)
+	if err := writeIndex(index, &sa.Index{L: l, BWT: b, Aux: aux}); err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	if err := run([]string{"unbwt", index}, nil, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out.Bytes(), bin) {
+		t.Errorf("unbwt of binary = %v, want %v", out.Bytes(), bin)
+	}
+
+	// index of lines divided by '\n', (1) is not a separator, the text is restored as it is with -lines
+	for _, args := range [][]string{{"unbwt", index}, {"unbwt", "-lines", index}} {
+		lines := []byte("ab\nb\x01a\nab\n")
+		l, b, aux := (&sa.Options{Separator: '\n'}).BWT(lines)
+		if err := writeIndex(index, &sa.Index{L: l, BWT: b, Aux: aux}); err != nil {
+			t.Fatal(err)
+		}
+		out.Reset()
+		if err := run(args, nil, &out); err != nil {
+			t.Fatal(err)
+		}
+		if want := string(lines); out.String() != want {
+			t.Errorf("%v of separator = %q, want %q", args, out.String(), want)
+		}
+	}
+
+	for _, width := range []int{4, 8} {
+		out.Reset()
+		if err := run([]string{"sa", "-width", string(rune('0' + width)), "-", "-"}, strings.NewReader("mississippi"), &out); err != nil {
+			t.Fatal(err)
+		}
+		want := sa.SuffixArray([]byte("mississippi"))
+		if out.Len() != width*len(want) {
+			t.Fatalf("sa -width %d wrote %d bytes", width, out.Len())
+		}
+		for i, p := range want {
+			var b [8]byte
+			copy(b[:], out.Bytes()[i*width:(i+1)*width])
+			if got := int(binary.LittleEndian.Uint64(b[:])); got != p {
+				t.Fatalf("sa -width %d [%d] = %d, want %d", width, i, got, p)
+			}
+		}
+	}
+
+	out.Reset()
+	if err := run([]string{"stats", "-lines", path}, nil, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "documents  4\n") || !strings.Contains(out.String(), "length     22\n") {
+		t.Errorf("stats = %q", out.String())
+	}
+
+	if err := run([]string{"bwt", "-", "-"}, strings.NewReader("a\x00b"), &out); err != sa.ErrReservedByte {
+		t.Errorf("bwt = %v, want %v", err, sa.ErrReservedByte)
+	}
+	if err := run([]string{"unknown"}, nil, nil); err != errUsage {
+		t.Errorf("unknown = %v, want %v", err, errUsage)
+	}
+}
+
+// writeIndex writes x to the file of path
+func writeIndex(path string, x *sa.Index) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := x.WriteTo(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
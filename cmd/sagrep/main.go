@@ -0,0 +1,254 @@
+/*
+ * Copyright 2020 Rock Lei Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Command sagrep searches substrings in files of a directory tree with an index.
+//
+// Usage:
+//
+//	sagrep build [-index file] [-rate 32] dir   index every file under dir
+//	sagrep [-index file] pattern                print path:line:text of every line containing pattern
+//
+// Every file is a document of a collection divided by separator, the index is a memory mapped
+// FM-index, see sa.OpenMapped, and a list of absolute paths of indexed files in index.files, the
+// index can be searched from any directory. Hidden directories, empty files and files containing
+// byte value (0) or (1) are skipped. Lines are read from the files when printed, files changed
+// since build are reported and skipped.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/rleiwang/sa"
+)
+
+const defaultIndex = ".sagrep"
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout, os.Stderr); err == flag.ErrHelp {
+		os.Exit(0)
+	} else if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+}
+
+// run executes args, hits are written to stdout, warnings to stderr
+func run(args []string, stdout, stderr io.Writer) error {
+	if len(args) > 0 && args[0] == "build" {
+		fs := flag.NewFlagSet("sagrep build", flag.ContinueOnError)
+		index := fs.String("index", defaultIndex, "index file")
+		rate := fs.Int("rate", 32, "suffix array sample rate")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		} else if fs.NArg() != 1 || *rate < 1 {
+			return errors.New("usage: sagrep build [-index file] [-rate 32] dir")
+		}
+		return build(fs.Arg(0), *index, *rate, stderr)
+	}
+
+	fs := flag.NewFlagSet("sagrep", flag.ContinueOnError)
+	index := fs.String("index", defaultIndex, "index file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	} else if fs.NArg() != 1 || fs.Arg(0) == "" {
+		return errors.New("usage: sagrep [-index file] pattern\n       sagrep build [-index file] [-rate 32] dir")
+	}
+	return search(*index, []byte(fs.Arg(0)), stdout, stderr)
+}
+
+// file indexed file, size detects changes since build
+type file struct {
+	path string
+	size int64
+}
+
+// build indexes every file under dir
+func build(dir, index string, rate int, stderr io.Writer) error {
+	abs, err := filepath.Abs(index)
+	if err != nil {
+		return err
+	}
+
+	var c sa.Collection
+	var files []file
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return nil
+		}
+		if info.IsDir() {
+			if path != dir && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		p, err := filepath.Abs(path)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return nil
+		} else if p == abs || p == abs+".files" {
+			return nil
+		}
+
+		data, err := ioutil.ReadFile(p)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return nil
+		}
+		// note: empty and binary files are not documents, Add accepts empty strings
+		if len(data) > 0 && c.Add(data) == nil {
+			files = append(files, file{p, int64(len(data))})
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if c.Len() == 0 {
+		return sa.ErrEmptyCollection
+	}
+
+	// note: suffix array is sampled from BWT, the full suffix array is never built
+	_, bwt, aux := sa.BWT(c.Text())
+	f := sa.NewFMIndex(bwt, aux)
+	f.Sample(rate)
+
+	out, err := os.Create(index)
+	if err != nil {
+		return err
+	}
+	if err := f.WriteMapped(out); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	// size and path divided by tab, every file ends with byte value (0), which paths can not contain
+	var list bytes.Buffer
+	for _, f := range files {
+		fmt.Fprintf(&list, "%d\t%s\x00", f.size, f.path)
+	}
+	return ioutil.WriteFile(index+".files", list.Bytes(), 0644)
+}
+
+// search prints every line containing pattern
+func search(index string, pattern []byte, stdout, stderr io.Writer) error {
+	files, err := readFiles(index + ".files")
+	if err != nil {
+		return err
+	}
+	m, err := sa.OpenMapped(index)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	w := bufio.NewWriter(stdout)
+	defer w.Flush()
+
+	// matches are sorted by document and offset
+	matches, err := m.LocateDocs(pattern)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < len(matches); {
+		doc, j := matches[i].Doc, i
+		for j < len(matches) && matches[j].Doc == doc {
+			j++
+		}
+		if doc >= len(files) {
+			return errors.New("sagrep: index does not match its file list")
+		}
+
+		f := files[doc]
+		data, err := ioutil.ReadFile(f.path)
+		if err != nil || int64(len(data)) != f.size {
+			fmt.Fprintf(stderr, "sagrep: %s changed since build, skipped\n", f.path)
+			i = j
+			continue
+		}
+
+		// line number and start of the current line, lines are printed once
+		line, start, last := 1, 0, -1
+		for _, match := range matches[i:j] {
+			o := match.Offset
+			line += bytes.Count(data[start:o], []byte{'\n'})
+			if k := bytes.LastIndexByte(data[:o], '\n'); k+1 > start {
+				start = k + 1
+			}
+			if line == last {
+				continue
+			}
+			last = line
+
+			end := bytes.IndexByte(data[o:], '\n')
+			if end < 0 {
+				end = len(data)
+			} else {
+				end += o
+			}
+			fmt.Fprintf(w, "%s:%d:%s\n", f.path, line, data[start:end])
+		}
+		i = j
+	}
+
+	return nil
+}
+
+func readFiles(path string) ([]file, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// note: a truncated list does not end with byte value (0)
+	if len(data) > 0 && data[len(data)-1] != 0 {
+		return nil, fmt.Errorf("sagrep: invalid file list %s", path)
+	}
+	var files []file
+	for _, l := range strings.Split(strings.TrimSuffix(string(data), "\x00"), "\x00") {
+		if l == "" {
+			continue
+		}
+		kv := strings.SplitN(l, "\t", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("sagrep: invalid file list %s", path)
+		}
+		size, err := strconv.ParseInt(kv[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("sagrep: invalid file list %s", path)
+		}
+		files = append(files, file{kv[1], size})
+	}
+
+	return files, nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSagrep(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sagrep")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	root := filepath.Join(dir, "root")
+	files := map[string]string{
+		"a.txt":        "hello world\nfoo bar\nhello hello\n",
+		"sub/b.go":     "package b\n\nfunc hello() {}",
+		"sub/bin":      "hello\x00binary",
+		"empty":        "",
+		".git/config":  "hello hidden",
+		"sub/deep/c.c": "no match here\n",
+		"new\nline":    "say hello\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	index := filepath.Join(dir, "index")
+	var stdout, stderr bytes.Buffer
+	if err := run([]string{"build", "-index", index, "-rate", "4", root}, &stdout, &stderr); err != nil {
+		t.Fatal(err)
+	}
+
+	// hidden, empty and binary files are skipped
+	list, err := readFiles(index + ".files")
+	if err != nil || len(list) != 4 {
+		t.Fatalf("readFiles() = %v, %v, want 4 files", list, err)
+	}
+	for _, f := range list {
+		if name, _ := filepath.Rel(root, f.path); name == "empty" || name == "sub/bin" || name == ".git/config" {
+			t.Errorf("readFiles() has %q", name)
+		}
+	}
+
+	if err := run([]string{"-index", index, "hello"}, &stdout, &stderr); err != nil {
+		t.Fatal(err)
+	}
+	want := filepath.Join(root, "a.txt") + ":1:hello world\n" +
+		filepath.Join(root, "a.txt") + ":3:hello hello\n" +
+		filepath.Join(root, "new\nline") + ":1:say hello\n" +
+		filepath.Join(root, "sub/b.go") + ":3:func hello() {}\n"
+	if got := stdout.String(); got != want {
+		t.Errorf("sagrep hello = %q, want %q", got, want)
+	}
+
+	// changed files are skipped
+	stdout.Reset()
+	ioutil.WriteFile(filepath.Join(root, "a.txt"), []byte("changed"), 0644)
+	if err := run([]string{"-index", index, "hello"}, &stdout, &stderr); err != nil {
+		t.Fatal(err)
+	}
+	want = filepath.Join(root, "new\nline") + ":1:say hello\n" + filepath.Join(root, "sub/b.go") + ":3:func hello() {}\n"
+	if got := stdout.String(); got != want {
+		t.Errorf("sagrep hello = %q, want %q", got, want)
+	}
+	if stderr.Len() == 0 {
+		t.Errorf("changed file is not reported")
+	}
+
+	// index built from a relative directory is searched from another directory
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	if err := os.Chdir(root); err != nil {
+		t.Fatal(err)
+	}
+	abs, _ := filepath.Abs("sub")
+	if err := run([]string{"build", "-index", index, "sub"}, &stdout, &stderr); err != nil {
+		t.Fatal(err)
+	}
+	os.Chdir(dir)
+	stdout.Reset()
+	if err := run([]string{"-index", index, "hello"}, &stdout, &stderr); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := stdout.String(), filepath.Join(abs, "b.go")+":3:func hello() {}\n"; got != want {
+		t.Errorf("sagrep hello = %q, want %q", got, want)
+	}
+}
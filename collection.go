@@ -0,0 +1,161 @@
+/*
+ * Copyright 2020 Rock Lei Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sa
+
+import (
+	"bytes"
+	"errors"
+)
+
+var (
+	// ErrReservedByte document contains sentinel or separator, byte value (0) or (1) unless Options is set
+	ErrReservedByte = errors.New("sa: document contains reserved byte value of sentinel or separator")
+
+	// ErrEmptyCollection collection has no document
+	ErrEmptyCollection = errors.New("sa: empty collection")
+)
+
+// Collection multi strings divided by separator
+type Collection struct {
+	// Options Workers, Separator and Sentinel of Build, nil is (1) and (0), Binary and Standard are ignored,
+	// it must not change after the first Add
+	Options *Options
+
+	text    []byte
+	offsets []int
+}
+
+// Transform BWT and suffix array of a collection
+type Transform struct {
+	// L row of the end of text in BWT
+	L int
+
+	// BWT of the text, see BWT
+	BWT []byte
+
+	// Aux auxiliary data structure of BWT
+	Aux *Aux
+
+	// SA suffix array of the text, see SuffixArray
+	SA []int
+
+	// Offsets start offset of each document in the text
+	Offsets []int
+}
+
+// Add appends doc to the collection, doc must not contain sentinel or separator, it can be empty
+func (c *Collection) Add(doc []byte) error {
+	o := c.options()
+	if bytes.IndexByte(doc, o.Sentinel) >= 0 || bytes.IndexByte(doc, o.separator()) >= 0 {
+		return ErrReservedByte
+	}
+
+	if len(c.offsets) > 0 {
+		c.text = append(c.text, o.separator())
+	}
+	c.offsets = append(c.offsets, len(c.text))
+	c.text = append(c.text, doc...)
+
+	return nil
+}
+
+// AddLines appends every line of data as a document, lines are divided by '\n', empty lines are empty
+// documents, '\n' at the end of data does not start another line
+func (c *Collection) AddLines(data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+	if data[len(data)-1] == '\n' {
+		data = data[:len(data)-1]
+	}
+	for _, line := range bytes.Split(data, []byte{'\n'}) {
+		if err := c.Add(line); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Len returns number of documents
+func (c *Collection) Len() int {
+	return len(c.offsets)
+}
+
+// Offsets returns the start offset of each document in the text
+func (c *Collection) Offsets() []int {
+	return c.offsets
+}
+
+// Text returns documents divided by separator, it must not be modified
+func (c *Collection) Text() []byte {
+	return c.text
+}
+
+// Build creates suffix array and BWT of the collection
+func (c *Collection) Build() (*Transform, error) {
+	if len(c.offsets) == 0 {
+		return nil, ErrEmptyCollection
+	}
+
+	o := c.options()
+	sa := o.SuffixArray(c.text)
+	l, bwt := bwtFromSA(c.text, sa)
+	offsets := make([]int, len(c.offsets))
+	copy(offsets, c.offsets)
+
+	// note: Aux is of the translated bytes, see Options.BWT
+	code, tr := o.alphabet(), bwt
+	if code != nil {
+		bwt[l] = o.Sentinel
+		tr = append([]byte(nil), bwt...)
+		translate(tr, code)
+	}
+	aux := newAux(tr, rankBWT(tr), dictBWT(tr))
+	if code != nil {
+		aux.Separator, aux.Sentinel = o.separator(), o.Sentinel
+	}
+
+	return &Transform{l, bwt, aux, sa, offsets}, nil
+}
+
+// options returns Options of the collection without Binary and Standard
+func (c *Collection) options() *Options {
+	o := &Options{}
+	if c.Options != nil {
+		o.Workers, o.Separator, o.Sentinel = c.Options.Workers, c.Options.Separator, c.Options.Sentinel
+	}
+	return o
+}
+
+// bwtFromSA returns the row of the end of text and BWT, same as BWT
+func bwtFromSA(t []byte, sa []int) (int, []byte) {
+	// row 0 is sentinel, followed by T[sa[i]+1], row 0 is also the end of empty text
+	l, bwt := 0, make([]byte, len(t)+1)
+	if len(t) > 0 {
+		bwt[0] = t[0]
+	}
+	for i, p := range sa {
+		if p+1 < len(t) {
+			bwt[i+1] = t[p+1]
+		} else {
+			l = i + 1
+		}
+	}
+
+	return l, bwt
+}
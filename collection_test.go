@@ -0,0 +1,127 @@
+package sa
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestCollection(t *testing.T) {
+	tests := []struct {
+		name    string
+		docs    []string
+		want    string
+		offsets []int
+		err     error
+	}{
+		{"one", []string{"sisisim"}, "sisisim", []int{0}, nil},
+		{"three", []string{"sisisim", "sisisim", "anana"}, "sisisim$sisisim$anana", []int{0, 8, 16}, nil},
+		{"empty", []string{""}, "", []int{0}, nil},
+		{"trailing", []string{"a", ""}, "a$", []int{0, 2}, nil},
+		{"leading", []string{"", "", "ab"}, "$$ab", []int{0, 1, 2}, nil},
+		{"consecutive", []string{"ab", "", "", "b"}, "ab$$$b", []int{0, 3, 4, 5}, nil},
+		{"separator", []string{"a\x01b"}, "", nil, ErrReservedByte},
+		{"zero", []string{"a", "\x00"}, "", nil, ErrReservedByte},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var c Collection
+			for _, d := range tt.docs {
+				if err := c.Add([]byte(d)); err != nil {
+					if err != tt.err {
+						t.Fatalf("Add(%q) = %v, want %v", d, err, tt.err)
+					}
+					return
+				}
+			}
+			if tt.err != nil {
+				t.Fatalf("Add() = nil, want %v", tt.err)
+			}
+
+			if got := toString(append([]byte{}, c.Text()...), 1, '$'); got != tt.want {
+				t.Errorf("Text() = %q, want %q", got, tt.want)
+			}
+			if got := c.Offsets(); !reflect.DeepEqual(got, tt.offsets) {
+				t.Errorf("Offsets() = %v, want %v", got, tt.offsets)
+			}
+
+			tr, err := c.Build()
+			if err != nil {
+				t.Fatalf("Build() = %v", err)
+			}
+			if !reflect.DeepEqual(tr.SA, naiveSA(c.Text())) {
+				t.Errorf("Build() SA = %v, want %v", tr.SA, naiveSA(c.Text()))
+			}
+			if !reflect.DeepEqual(tr.Offsets, tt.offsets) {
+				t.Errorf("Build() Offsets = %v, want %v", tr.Offsets, tt.offsets)
+			}
+			if got := toString(InverseBWT(tr.BWT, tr.L), 1, '$'); got != tt.want {
+				t.Errorf("InverseBWT() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := new(Collection).Build(); err != ErrEmptyCollection {
+		t.Errorf("Build() = %v, want %v", err, ErrEmptyCollection)
+	}
+}
+
+func TestCollectionAddLines(t *testing.T) {
+	var c Collection
+	if err := c.AddLines([]byte("sisisim\n\nsisisim\nanana\n")); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := toString(append([]byte{}, c.Text()...), 1, '$'), "sisisim$$sisisim$anana"; got != want {
+		t.Errorf("Text() = %q, want %q", got, want)
+	}
+	if err := c.AddLines([]byte("a\x01b\n")); err != ErrReservedByte {
+		t.Errorf("AddLines() = %v, want %v", err, ErrReservedByte)
+	}
+}
+
+func TestCollectionBWT(t *testing.T) {
+	r := rand.New(rand.NewSource(7))
+	for i := 0; i < 200; i++ {
+		var c Collection
+		for j, n := 0, 1+r.Intn(5); j < n; j++ {
+			if err := c.Add(randText(r, r.Intn(20), 1+r.Intn(4), false)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		tr, err := c.Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		l, bwt, aux := BWT(append([]byte{}, c.Text()...))
+		if tr.L != l || !reflect.DeepEqual(tr.BWT, bwt) || !reflect.DeepEqual(tr.Aux, aux) {
+			t.Fatalf("Build(%q) = %v, %v, want %v, %v", toString(append([]byte{}, c.Text()...), 1, '$'), tr.L, tr.BWT, l, bwt)
+		}
+	}
+}
+
+func TestCollectionOptions(t *testing.T) {
+	o := &Options{Separator: '\n', Sentinel: '#'}
+	c := Collection{Options: o}
+	if err := c.AddLines([]byte("sisisim\n\nsis\x01sim\nanana\n")); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(c.Text()), "sisisim\n\nsis\x01sim\nanana"; got != want {
+		t.Errorf("Text() = %q, want %q", got, want)
+	}
+	if err := c.Add([]byte("a#b")); err != ErrReservedByte {
+		t.Errorf("Add() = %v, want %v", err, ErrReservedByte)
+	}
+
+	tr, err := c.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, bwt, aux := o.BWT(append([]byte{}, c.Text()...))
+	if tr.L != l || !reflect.DeepEqual(tr.BWT, bwt) || !reflect.DeepEqual(tr.Aux, aux) {
+		t.Fatalf("Build() = %d, %q, want %d, %q", tr.L, tr.BWT, l, bwt)
+	}
+	if !reflect.DeepEqual(tr.SA, o.SuffixArray(c.Text())) {
+		t.Errorf("Build() SA = %v, want %v", tr.SA, o.SuffixArray(c.Text()))
+	}
+}
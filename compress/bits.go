@@ -0,0 +1,91 @@
+/*
+ * Copyright 2020 Rock Lei Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package compress
+
+import "sync"
+
+// bitWriter appends bits to a byte slice, most significant bit first
+type bitWriter struct {
+	buf  []byte
+	acc  uint64
+	bits uint
+}
+
+// write appends the lowest n bits of v, n <= 32
+func (w *bitWriter) write(v uint32, n uint) {
+	w.acc = w.acc<<n | uint64(v)&(1<<n-1)
+	w.bits += n
+	for w.bits >= 8 {
+		w.bits -= 8
+		w.buf = append(w.buf, byte(w.acc>>w.bits))
+	}
+}
+
+// flush pads the last byte with zeros
+func (w *bitWriter) flush() []byte {
+	if w.bits > 0 {
+		w.write(0, 8-w.bits)
+	}
+	return w.buf
+}
+
+// append writes the bits of o
+func (w *bitWriter) append(o *bitWriter) {
+	if w.bits == 0 {
+		w.buf = append(w.buf, o.buf...)
+	} else {
+		for _, b := range o.buf {
+			w.write(uint32(b), 8)
+		}
+	}
+	w.write(uint32(o.acc), o.bits)
+}
+
+// writers recycles the bit writers of encoded blocks, blocks are encoded into buffers of similar size
+var writers = sync.Pool{New: func() interface{} { return new(bitWriter) }}
+
+// getBitWriter returns an empty bit writer from the pool
+func getBitWriter() *bitWriter {
+	return writers.Get().(*bitWriter)
+}
+
+// release resets w and returns it to the pool, w must not be used afterwards
+func (w *bitWriter) release() {
+	w.buf, w.acc, w.bits = w.buf[:0], 0, 0
+	writers.Put(w)
+}
+
+// bitReader reads bits from a byte slice, most significant bit first
+type bitReader struct {
+	buf  []byte
+	acc  uint64
+	bits uint
+}
+
+// read returns the next n bits, n <= 32, ok is false if there are not enough bits
+func (r *bitReader) read(n uint) (uint32, bool) {
+	for r.bits < n {
+		if len(r.buf) == 0 {
+			return 0, false
+		}
+		r.acc = r.acc<<8 | uint64(r.buf[0])
+		r.buf = r.buf[1:]
+		r.bits += 8
+	}
+	r.bits -= n
+	return uint32(r.acc>>r.bits) & (1<<n - 1), true
+}
@@ -0,0 +1,252 @@
+/*
+ * Copyright 2020 Rock Lei Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package compress
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+
+	"github.com/rleiwang/sa"
+)
+
+const (
+	// escape byte, bytes 0, 1 and 2 are reserved by BWT or escape, and written as escape, byte + 3
+	escape = 2
+
+	// symbols after move-to-front, RUNA and RUNB encode runs of zeros, byte k > 0 is symbol k + 1
+	runA    = 0
+	runB    = 1
+	eob     = 257
+	numSyms = 258
+
+	// bits of each code length in block header
+	lenBits = 5
+)
+
+// escapeBlock escapes reserved bytes of data, the result has no byte 0 or 1
+func escapeBlock(data []byte) []byte {
+	// one more byte for the end of text of BWT in place
+	m := len(data)
+	for _, c := range data {
+		if c <= escape {
+			m++
+		}
+	}
+	t := make([]byte, 0, m+1)
+	for _, c := range data {
+		if c <= escape {
+			t = append(t, escape, c+escape+1)
+		} else {
+			t = append(t, c)
+		}
+	}
+
+	return t
+}
+
+// unescapeBlock reverses escapeBlock, ok is false if t is not escaped properly
+func unescapeBlock(t []byte, n int) ([]byte, bool) {
+	data := make([]byte, 0, n)
+	for i := 0; i < len(t); i++ {
+		c := t[i]
+		if c == escape {
+			if i++; i == len(t) || t[i] <= escape || t[i] > 2*escape+1 {
+				return nil, false
+			}
+			c = t[i] - escape - 1
+		} else if c < escape {
+			return nil, false
+		}
+		data = append(data, c)
+	}
+
+	return data, len(data) == n
+}
+
+// encodeBlock compresses data, appends the payload of the block to w:
+// crc32 of data, the row of end of text, the length of the escaped text, code lengths and huffman codes
+func encodeBlock(w *bitWriter, data []byte) {
+	t := escapeBlock(data)
+	m := len(t)
+	// t is not used afterwards, BWT in place, escapeBlock leaves room for the end of text
+	l, bwt, _ := (&sa.Options{InPlace: true}).BWT(t)
+
+	// move-to-front and zero-run-length coding, end of text is dropped, it is restored by l
+	var mtf [256]byte
+	for i := range mtf {
+		mtf[i] = byte(i)
+	}
+	syms, zeros := make([]uint16, 0, m/2+1), 0
+	flush := func() {
+		// bijective base 2, RUNA is digit 1, RUNB is digit 2
+		for ; zeros > 0; zeros = (zeros - 1) >> 1 {
+			syms = append(syms, uint16(runA+(zeros-1)&1))
+		}
+	}
+	for i, c := range bwt {
+		if i == l {
+			continue
+		}
+		k := 0
+		for mtf[k] != c {
+			k++
+		}
+		if k == 0 {
+			zeros++
+			continue
+		}
+		flush()
+		copy(mtf[1:k+1], mtf[:k])
+		mtf[0] = c
+		syms = append(syms, uint16(k+1))
+	}
+	flush()
+	syms = append(syms, eob)
+
+	freqs := make([]int, numSyms)
+	for _, s := range syms {
+		freqs[s]++
+	}
+	// note: huffman needs at least two symbols
+	if freqs[runA] == 0 {
+		freqs[runA] = 1
+	}
+	lens := codeLengths(freqs, maxCodeLen)
+	codes := canonicalCodes(lens)
+
+	var sum [4]byte
+	binary.BigEndian.PutUint32(sum[:], crc32.ChecksumIEEE(data))
+	w.buf = append(w.buf, sum[:]...)
+	w.buf = appendUvarint(w.buf, uint64(l))
+	w.buf = appendUvarint(w.buf, uint64(m))
+	for _, n := range lens {
+		w.write(uint32(n), lenBits)
+	}
+	for _, s := range syms {
+		w.write(codes[s], uint(lens[s]))
+	}
+	w.flush()
+}
+
+// decodeBlock decompresses payload of a block of n bytes
+func decodeBlock(payload []byte, n int) ([]byte, error) {
+	if len(payload) < 4 {
+		return nil, ErrFormat
+	}
+	sum, buf := binary.BigEndian.Uint32(payload), payload[4:]
+	l, k := binary.Uvarint(buf)
+	if k <= 0 {
+		return nil, ErrFormat
+	}
+	buf = buf[k:]
+	m, k := binary.Uvarint(buf)
+	if k <= 0 || m < uint64(n) || m > 2*uint64(n) || l > m {
+		return nil, ErrFormat
+	}
+
+	r := &bitReader{buf: buf[k:]}
+	lens := make([]uint8, numSyms)
+	for s := range lens {
+		v, ok := r.read(lenBits)
+		if !ok || v > maxCodeLen {
+			return nil, ErrFormat
+		}
+		lens[s] = uint8(v)
+	}
+	d := newDecoder(lens)
+
+	// bwt has m + 1 rows, end of text at row l
+	var mtf [256]byte
+	for i := range mtf {
+		mtf[i] = byte(i)
+	}
+	bwt := make([]byte, 0, m+1)
+	emit := func(c byte, cnt int) bool {
+		for ; cnt > 0; cnt-- {
+			if len(bwt) == int(l) {
+				bwt = append(bwt, 0)
+			}
+			if len(bwt) > int(m) {
+				return false
+			}
+			bwt = append(bwt, c)
+		}
+		return true
+	}
+	zeros, digit := 0, 1
+	for {
+		s := d.decode(r)
+		if s < 0 {
+			return nil, ErrFormat
+		}
+		if s == runA || s == runB {
+			zeros += digit << uint(s)
+			if digit <<= 1; zeros > int(m) {
+				return nil, ErrFormat
+			}
+			continue
+		}
+		if !emit(mtf[0], zeros) {
+			return nil, ErrFormat
+		}
+		zeros, digit = 0, 1
+		if s == eob {
+			break
+		}
+
+		k := s - 1
+		c := mtf[k]
+		copy(mtf[1:k+1], mtf[:k])
+		mtf[0] = c
+		if !emit(c, 1) {
+			return nil, ErrFormat
+		}
+	}
+	if len(bwt) == int(l) {
+		bwt = append(bwt, 0)
+	}
+	if len(bwt) != int(m)+1 || !validBWT(bwt, int(l)) {
+		return nil, ErrFormat
+	}
+
+	data, ok := unescapeBlock(sa.InverseBWT(bwt, int(l)), n)
+	if !ok {
+		return nil, ErrFormat
+	}
+	if crc32.ChecksumIEEE(data) != sum {
+		return nil, ErrChecksum
+	}
+
+	return data, nil
+}
+
+// validBWT checks the only end of text is at row l, and no separators, LF mapping of such bwt from
+// row 0 always reaches row l, so that InverseBWT does not panic on corrupted input
+func validBWT(bwt []byte, l int) bool {
+	for i, c := range bwt {
+		if (c == 0) != (i == l) || c == 1 {
+			return false
+		}
+	}
+
+	return true
+}
+
+func appendUvarint(buf []byte, v uint64) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+	return append(buf, tmp[:binary.PutUvarint(tmp[:], v)]...)
+}
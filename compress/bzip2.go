@@ -0,0 +1,391 @@
+/*
+ * Copyright 2020 Rock Lei Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package compress
+
+import (
+	"io"
+
+	"github.com/rleiwang/sa"
+)
+
+const (
+	bzBlockMagic = 0x314159265359
+	bzEndMagic   = 0x177245385090
+
+	// bzip2 limits code length to 17, groups of 50 symbols share a huffman table
+	bzMaxCodeLen = 17
+	bzGroupSize  = 50
+
+	// passes to refine huffman tables
+	bzIterations = 4
+)
+
+// bzCRCTable crc32 of bzip2, most significant bit first, polynomial 0x04c11db7
+var bzCRCTable = func() (tab [256]uint32) {
+	for i := range tab {
+		c := uint32(i) << 24
+		for j := 0; j < 8; j++ {
+			if c&0x80000000 != 0 {
+				c = c<<1 ^ 0x04c11db7
+			} else {
+				c <<= 1
+			}
+		}
+		tab[i] = c
+	}
+	return
+}()
+
+// Bzip2Writer compresses data written to it in bzip2 format, which can be read by compress/bzip2.
+// Blocks are sorted by RotationBWT, Close must be called to flush the last block.
+type Bzip2Writer struct {
+	w     io.Writer
+	level int
+	bw    bitWriter
+
+	// block run-length encoded bytes of the current block, at most max bytes
+	block []byte
+	max   int
+
+	// crc of the raw bytes of the current block, combined crc of all blocks
+	crc, combined uint32
+
+	// pending run of byte last, not yet encoded into block
+	last byte
+	run  int
+
+	pipe *pipeline
+
+	header, closed bool
+	err            error
+}
+
+// NewBzip2Writer returns Bzip2Writer, level from 1 to 9 is the block size in 100k bytes
+func NewBzip2Writer(w io.Writer, level int) *Bzip2Writer {
+	return NewBzip2WriterWorkers(w, level, 1)
+}
+
+// NewBzip2WriterWorkers returns Bzip2Writer compressing up to workers blocks concurrently,
+// the output is identical to NewBzip2Writer
+func NewBzip2WriterWorkers(w io.Writer, level, workers int) *Bzip2Writer {
+	if level < 1 || level > 9 {
+		panic("compress: bzip2 level must be from 1 to 9")
+	}
+	// note: bzip2 reserves 19 bytes of the block
+	return &Bzip2Writer{w: w, level: level, max: level*100000 - 19, crc: ^uint32(0), pipe: newPipeline(workers)}
+}
+
+// Write compresses p, a block is written when it is full
+func (z *Bzip2Writer) Write(p []byte) (int, error) {
+	if z.closed {
+		return 0, errClosed
+	}
+
+	for _, c := range p {
+		if z.run > 0 && c == z.last && z.run < 255 {
+			z.run++
+			continue
+		}
+		if z.run > 0 {
+			z.encodeRun()
+		}
+		z.last, z.run = c, 1
+	}
+
+	return len(p), z.err
+}
+
+// Close writes the last block and the end of stream, it does not close the underlying writer
+func (z *Bzip2Writer) Close() error {
+	if z.closed {
+		return z.err
+	}
+	z.closed = true
+
+	if z.run > 0 {
+		z.encodeRun()
+	}
+	if len(z.block) > 0 {
+		z.flush()
+	}
+	z.pipe.drain(z.emit)
+	z.writeHeader()
+	z.bw.write(bzEndMagic>>24, 24)
+	z.bw.write(bzEndMagic&0xffffff, 24)
+	z.bw.write(z.combined, 32)
+	z.write(z.bw.flush())
+
+	return z.err
+}
+
+// encodeRun appends the pending run to block, runs of 4 to 255 bytes are 4 bytes and the number of
+// remaining bytes
+func (z *Bzip2Writer) encodeRun() {
+	if len(z.block)+5 > z.max {
+		z.flush()
+	}
+
+	c, n := z.last, z.run
+	for i := 0; i < n; i++ {
+		z.crc = z.crc<<8 ^ bzCRCTable[byte(z.crc>>24)^c]
+	}
+	if n < 4 {
+		for ; n > 0; n-- {
+			z.block = append(z.block, c)
+		}
+	} else {
+		z.block = append(z.block, c, c, c, c, byte(n-4))
+	}
+	z.run = 0
+}
+
+// flush submits the current block to compress
+func (z *Bzip2Writer) flush() {
+	block, crc := z.block, ^z.crc
+	z.combined = (z.combined<<1 | z.combined>>31) ^ crc
+	z.pipe.submit(func() *bitWriter {
+		w := getBitWriter()
+		encodeBzBlock(w, block, crc)
+		return w
+	}, z.emit)
+	z.block, z.crc = nil, ^uint32(0)
+}
+
+// emit writes a compressed block, blocks are not byte aligned
+func (z *Bzip2Writer) emit(b *bitWriter) {
+	z.writeHeader()
+	z.bw.append(b)
+	b.release()
+
+	// write whole bytes, keep the partial byte
+	z.write(z.bw.buf)
+	z.bw.buf = z.bw.buf[:0]
+}
+
+func (z *Bzip2Writer) writeHeader() {
+	if !z.header {
+		z.header = true
+		z.write([]byte{'B', 'Z', 'h', byte('0' + z.level)})
+	}
+}
+
+func (z *Bzip2Writer) write(p []byte) {
+	if z.err == nil {
+		_, z.err = z.w.Write(p)
+	}
+}
+
+// encodeBzBlock writes a bzip2 block of run-length encoded bytes, crc is crc of the raw bytes
+func encodeBzBlock(w *bitWriter, block []byte, crc uint32) {
+	l, bwt := sa.RotationBWT(block)
+
+	// bytes in use, move-to-front runs over bytes in use only
+	var inUse [256]bool
+	for _, c := range block {
+		inUse[c] = true
+	}
+	var mtf []byte
+	for c, ok := range inUse {
+		if ok {
+			mtf = append(mtf, byte(c))
+		}
+	}
+	alphaSize := len(mtf) + 2
+	end := uint16(alphaSize - 1)
+
+	// same coding as encodeBlock, byte k > 0 is symbol k + 1
+	syms, zeros := make([]uint16, 0, len(bwt)/2+1), 0
+	flush := func() {
+		for ; zeros > 0; zeros = (zeros - 1) >> 1 {
+			syms = append(syms, uint16(runA+(zeros-1)&1))
+		}
+	}
+	for _, c := range bwt {
+		k := 0
+		for mtf[k] != c {
+			k++
+		}
+		if k == 0 {
+			zeros++
+			continue
+		}
+		flush()
+		copy(mtf[1:k+1], mtf[:k])
+		mtf[0] = c
+		syms = append(syms, uint16(k+1))
+	}
+	flush()
+	syms = append(syms, end)
+
+	lens, selectors := bzTables(syms, alphaSize)
+
+	w.write(bzBlockMagic>>24, 24)
+	w.write(bzBlockMagic&0xffffff, 24)
+	w.write(crc, 32)
+	// not randomised
+	w.write(0, 1)
+	w.write(uint32(l), 24)
+
+	// bitmap of 16 ranges of 16 bytes, then bitmap of each range in use
+	var ranges uint32
+	for i := 0; i < 16; i++ {
+		for _, ok := range inUse[i*16 : i*16+16] {
+			if ok {
+				ranges |= 1 << uint(15-i)
+				break
+			}
+		}
+	}
+	w.write(ranges, 16)
+	for i := 0; i < 16; i++ {
+		if ranges&(1<<uint(15-i)) != 0 {
+			var bits uint32
+			for j, ok := range inUse[i*16 : i*16+16] {
+				if ok {
+					bits |= 1 << uint(15-j)
+				}
+			}
+			w.write(bits, 16)
+		}
+	}
+
+	// selectors are move-to-front coded in unary
+	w.write(uint32(len(lens)), 3)
+	w.write(uint32(len(selectors)), 15)
+	order := []byte{0, 1, 2, 3, 4, 5}
+	for _, s := range selectors {
+		k := 0
+		for order[k] != s {
+			k++
+		}
+		copy(order[1:k+1], order[:k])
+		order[0] = s
+		for ; k > 0; k-- {
+			w.write(1, 1)
+		}
+		w.write(0, 1)
+	}
+
+	// code lengths are delta coded, 10 increments, 11 decrements, 0 moves to the next symbol
+	codes := make([][]uint32, len(lens))
+	for t, tab := range lens {
+		codes[t] = canonicalCodes(tab)
+		cur := tab[0]
+		w.write(uint32(cur), 5)
+		for _, n := range tab {
+			for ; cur < n; cur++ {
+				w.write(2, 2)
+			}
+			for ; cur > n; cur-- {
+				w.write(3, 2)
+			}
+			w.write(0, 1)
+		}
+	}
+
+	for i, s := range syms {
+		t := selectors[i/bzGroupSize]
+		w.write(codes[t][s], uint(lens[t][s]))
+	}
+}
+
+// bzTables chooses huffman tables for groups of symbols, returns code lengths of every table,
+// and the table selected by every group
+func bzTables(syms []uint16, alphaSize int) ([][]uint8, []byte) {
+	var nTables int
+	switch n := len(syms); {
+	case n < 200:
+		nTables = 2
+	case n < 600:
+		nTables = 3
+	case n < 1200:
+		nTables = 4
+	case n < 2400:
+		nTables = 5
+	default:
+		nTables = 6
+	}
+
+	// initial tables split symbols into ranges of about equal frequencies, cheap inside the range
+	freqs := make([]int, alphaSize)
+	for _, s := range syms {
+		freqs[s]++
+	}
+	lens := make([][]uint8, nTables)
+	remain, lo := len(syms), 0
+	for t := nTables; t > 0; t-- {
+		target, sum, hi := remain/t, 0, lo
+		for hi < alphaSize && (sum < target || hi == lo) {
+			sum += freqs[hi]
+			hi++
+		}
+		// note: give back the last symbol of odd ranges as bzip2 does, avoids lopsided first table
+		if hi > lo+1 && t != nTables && t != 1 && (nTables-t)%2 == 1 {
+			hi--
+			sum -= freqs[hi]
+		}
+
+		tab := make([]uint8, alphaSize)
+		for s := range tab {
+			if s < lo || s >= hi {
+				tab[s] = 15
+			}
+		}
+		lens[nTables-t] = tab
+		remain, lo = remain-sum, hi
+	}
+
+	selectors := make([]byte, (len(syms)+bzGroupSize-1)/bzGroupSize)
+	for it := 0; it < bzIterations; it++ {
+		tfreqs := make([][]int, nTables)
+		for t := range tfreqs {
+			tfreqs[t] = make([]int, alphaSize)
+		}
+
+		for g := range selectors {
+			group := syms[g*bzGroupSize:]
+			if len(group) > bzGroupSize {
+				group = group[:bzGroupSize]
+			}
+
+			best, cost := 0, -1
+			for t, tab := range lens {
+				c := 0
+				for _, s := range group {
+					c += int(tab[s])
+				}
+				if cost < 0 || c < cost {
+					best, cost = t, c
+				}
+			}
+			selectors[g] = byte(best)
+			for _, s := range group {
+				tfreqs[best][s]++
+			}
+		}
+
+		// every symbol needs a code, unused tables get flat codes
+		for t, f := range tfreqs {
+			for s := range f {
+				f[s]++
+			}
+			lens[t] = codeLengths(f, bzMaxCodeLen)
+		}
+	}
+
+	return lens, selectors
+}
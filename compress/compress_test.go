@@ -0,0 +1,187 @@
+package compress
+
+import (
+	"bytes"
+	"compress/bzip2"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"testing"
+)
+
+func roundTrip(t *testing.T, data []byte, blockSize int) []byte {
+	var buf bytes.Buffer
+	w := NewWriterSize(&buf, blockSize)
+	// write in pieces to cross block boundaries
+	for p := data; len(p) > 0; {
+		k := 1 + len(p)/3
+		if _, err := w.Write(p[:k]); err != nil {
+			t.Fatal(err)
+		}
+		p = p[k:]
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ioutil.ReadAll(NewReader(bytes.NewReader(buf.Bytes())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatalf("round trip of %d bytes, block size %d, got %d bytes", len(data), blockSize, len(got))
+	}
+
+	return buf.Bytes()
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, s := range []string{"", "a", "\x00", "\x01\x02", "aaaaaaaaaa", "banana", "mississippi\n"} {
+		roundTrip(t, []byte(s), 4)
+		roundTrip(t, []byte(s), DefaultBlockSize)
+	}
+
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 50; i++ {
+		data := make([]byte, r.Intn(5000))
+		k := 1 + r.Intn(256)
+		for j := range data {
+			data[j] = byte(r.Intn(k))
+		}
+		roundTrip(t, data, 1+r.Intn(3000))
+	}
+}
+
+func TestCompressible(t *testing.T) {
+	var data []byte
+	for i := 0; i < 2000; i++ {
+		data = append(data, "the quick brown fox jumps over the lazy dog\n"...)
+	}
+	if out := roundTrip(t, data, DefaultBlockSize); len(out)*20 > len(data) {
+		t.Fatalf("compressed %d bytes to %d bytes", len(data), len(out))
+	}
+}
+
+func TestCorrupted(t *testing.T) {
+	data := bytes.Repeat([]byte("abracadabra"), 100)
+	out := roundTrip(t, data, 256)
+
+	if _, err := ioutil.ReadAll(NewReader(bytes.NewReader(out[:len(out)-1]))); err != io.ErrUnexpectedEOF {
+		t.Fatalf("truncated stream, err = %v", err)
+	}
+	if _, err := ioutil.ReadAll(NewReader(bytes.NewReader([]byte("SAZ\x09")))); err != ErrFormat {
+		t.Fatalf("bad version, err = %v", err)
+	}
+
+	// a corrupted payload size is not allocated before the stream runs out
+	hdr := append(append([]byte{}, magic...), version)
+	hdr = appendUvarint(hdr, 11)
+	hdr = appendUvarint(hdr, 2*maxBlockSize)
+	if _, err := ioutil.ReadAll(NewReader(bytes.NewReader(append(hdr, out[4:]...)))); err != io.ErrUnexpectedEOF {
+		t.Fatalf("corrupted payload size, err = %v", err)
+	}
+
+	// flipped bits must never panic or hang
+	r := rand.New(rand.NewSource(2))
+	for i := 0; i < 200; i++ {
+		bad := append([]byte{}, out...)
+		bad[4+r.Intn(len(bad)-4)] ^= byte(1 << uint(r.Intn(8)))
+		if got, err := ioutil.ReadAll(NewReader(bytes.NewReader(bad))); err == nil && !bytes.Equal(got, data) {
+			t.Fatalf("corrupted stream decoded without error")
+		}
+	}
+}
+
+func TestCodeLengths(t *testing.T) {
+	// fibonacci frequencies make the deepest tree
+	freqs := make([]int, 40)
+	for i, a, b := 0, 1, 1; i < len(freqs); i, a, b = i+1, b, a+b {
+		freqs[i] = a
+	}
+	lens := codeLengths(freqs, maxCodeLen)
+
+	kraft := 0.0
+	for _, l := range lens {
+		if l == 0 || l > maxCodeLen {
+			t.Fatalf("code lengths %v", lens)
+		}
+		kraft += 1 / float64(uint(1)<<l)
+	}
+	if kraft > 1 {
+		t.Fatalf("code lengths %v, kraft sum %f", lens, kraft)
+	}
+}
+
+func TestBzip2Writer(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	inputs := [][]byte{nil, []byte("a"), bytes.Repeat([]byte{'x'}, 1000), bytes.Repeat([]byte("abcd"), 300000)}
+	for i := 0; i < 30; i++ {
+		data := make([]byte, r.Intn(20000))
+		k := 1 + r.Intn(256)
+		for j := range data {
+			if j > 0 && r.Intn(4) == 0 {
+				// long runs of the same byte
+				data[j] = data[j-1]
+			} else {
+				data[j] = byte(r.Intn(k))
+			}
+		}
+		inputs = append(inputs, data)
+	}
+
+	for i, data := range inputs {
+		var buf bytes.Buffer
+		w := NewBzip2Writer(&buf, 1+i%9)
+		if _, err := w.Write(data); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := ioutil.ReadAll(bzip2.NewReader(&buf))
+		if err != nil {
+			t.Fatalf("input %d, %v", i, err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Fatalf("input %d of %d bytes, got %d bytes", i, len(data), len(got))
+		}
+	}
+}
+
+func TestWorkers(t *testing.T) {
+	r := rand.New(rand.NewSource(4))
+	data := make([]byte, 300000)
+	for j := range data {
+		data[j] = byte('a' + r.Intn(3+j/50000))
+	}
+
+	var one, many bytes.Buffer
+	w := NewWriterSize(&one, 7000)
+	w.Write(data)
+	w.Close()
+	w = NewWriterWorkers(&many, 7000, 4)
+	w.Write(data)
+	w.Close()
+	if !bytes.Equal(one.Bytes(), many.Bytes()) {
+		t.Fatalf("output of 4 workers differs from 1 worker")
+	}
+
+	one.Reset()
+	many.Reset()
+	bz := NewBzip2Writer(&one, 1)
+	bz.Write(data)
+	bz.Write(data)
+	bz.Close()
+	bz = NewBzip2WriterWorkers(&many, 1, 3)
+	bz.Write(data)
+	bz.Write(data)
+	bz.Close()
+	if !bytes.Equal(one.Bytes(), many.Bytes()) {
+		t.Fatalf("bzip2 output of 3 workers differs from 1 worker")
+	}
+	got, err := ioutil.ReadAll(bzip2.NewReader(&many))
+	if err != nil || !bytes.Equal(got, append(data, data...)) {
+		t.Fatalf("bzip2 round trip, err = %v", err)
+	}
+}
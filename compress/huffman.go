@@ -0,0 +1,157 @@
+/*
+ * Copyright 2020 Rock Lei Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package compress
+
+import "sort"
+
+// maximum length of huffman code
+const maxCodeLen = 20
+
+// codeLengths returns length of huffman code of every symbol, no longer than maxLen,
+// symbols with zero frequency have no code. Note: at least two symbols must have frequency.
+func codeLengths(freqs []int, maxLen uint8) []uint8 {
+	type node struct {
+		freq        int
+		left, right int
+	}
+
+	lens := make([]uint8, len(freqs))
+	weights := make([]int, len(freqs))
+	copy(weights, freqs)
+	for {
+		// leaves are sorted by frequency, internal nodes are created in ascending frequency,
+		// two queues merge the two least frequent nodes
+		var nodes []node
+		var leaves []int
+		for s, f := range weights {
+			if f > 0 {
+				nodes = append(nodes, node{f, -1, s})
+				leaves = append(leaves, len(nodes)-1)
+			}
+		}
+		sort.SliceStable(leaves, func(i, j int) bool { return nodes[leaves[i]].freq < nodes[leaves[j]].freq })
+
+		var internal []int
+		pop := func() int {
+			if len(internal) == 0 || (len(leaves) > 0 && nodes[leaves[0]].freq <= nodes[internal[0]].freq) {
+				n := leaves[0]
+				leaves = leaves[1:]
+				return n
+			}
+			n := internal[0]
+			internal = internal[1:]
+			return n
+		}
+		for len(leaves)+len(internal) > 1 {
+			x, y := pop(), pop()
+			nodes = append(nodes, node{nodes[x].freq + nodes[y].freq, x, y})
+			internal = append(internal, len(nodes)-1)
+		}
+
+		// depth of leaves, left < 0 marks leaf, right is its symbol
+		longest := uint8(0)
+		var walk func(n int, depth uint8)
+		walk = func(n int, depth uint8) {
+			if nodes[n].left < 0 {
+				lens[nodes[n].right] = depth
+				if depth > longest {
+					longest = depth
+				}
+				return
+			}
+			walk(nodes[n].left, depth+1)
+			walk(nodes[n].right, depth+1)
+		}
+		walk(len(nodes)-1, 0)
+
+		if longest <= maxLen {
+			return lens
+		}
+
+		// flatten frequencies and try again
+		for s, f := range weights {
+			if f > 0 {
+				weights[s] = f/2 + 1
+			}
+		}
+	}
+}
+
+// canonicalCodes assigns codes in the order of length then symbol
+func canonicalCodes(lens []uint8) []uint32 {
+	var count [maxCodeLen + 1]uint32
+	for _, l := range lens {
+		count[l]++
+	}
+	count[0] = 0
+
+	var next [maxCodeLen + 2]uint32
+	for l := 1; l <= maxCodeLen; l++ {
+		next[l+1] = (next[l] + count[l]) << 1
+	}
+
+	codes := make([]uint32, len(lens))
+	for s, l := range lens {
+		if l > 0 {
+			codes[s] = next[l]
+			next[l]++
+		}
+	}
+
+	return codes
+}
+
+// decoder decodes canonical huffman codes
+type decoder struct {
+	count [maxCodeLen + 1]int
+	syms  []int
+}
+
+func newDecoder(lens []uint8) *decoder {
+	d := &decoder{}
+	for s, l := range lens {
+		if l > 0 {
+			d.count[l]++
+			d.syms = append(d.syms, s)
+		}
+	}
+	sort.SliceStable(d.syms, func(i, j int) bool { return lens[d.syms[i]] < lens[d.syms[j]] })
+
+	return d
+}
+
+// decode returns the next symbol, -1 if bits are exhausted or invalid
+func (d *decoder) decode(r *bitReader) int {
+	code, first, index := 0, 0, 0
+	for l := 1; l <= maxCodeLen; l++ {
+		b, ok := r.read(1)
+		if !ok {
+			return -1
+		}
+		code |= int(b)
+		if n := d.count[l]; code-first < n {
+			return d.syms[index+code-first]
+		} else {
+			index += n
+			first += n
+		}
+		first <<= 1
+		code <<= 1
+	}
+
+	return -1
+}
@@ -0,0 +1,57 @@
+/*
+ * Copyright 2020 Rock Lei Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package compress
+
+// pipeline encodes blocks in goroutines, at most workers blocks are in flight,
+// encoded blocks are emitted in the order they are submitted
+type pipeline struct {
+	workers int
+	pending []chan *bitWriter
+}
+
+func newPipeline(workers int) *pipeline {
+	if workers < 1 {
+		panic("compress: number of workers must be positive")
+	}
+	return &pipeline{workers: workers}
+}
+
+// submit starts encode in a goroutine, waits for the oldest block if all workers are busy
+func (p *pipeline) submit(encode func() *bitWriter, emit func(*bitWriter)) {
+	if len(p.pending) == p.workers {
+		p.next(emit)
+	}
+
+	c := make(chan *bitWriter, 1)
+	p.pending = append(p.pending, c)
+	go func() {
+		c <- encode()
+	}()
+}
+
+// drain waits for all blocks in flight
+func (p *pipeline) drain(emit func(*bitWriter)) {
+	for len(p.pending) > 0 {
+		p.next(emit)
+	}
+}
+
+func (p *pipeline) next(emit func(*bitWriter)) {
+	emit(<-p.pending[0])
+	p.pending[0] = nil
+	p.pending = p.pending[1:]
+}
@@ -0,0 +1,133 @@
+/*
+ * Copyright 2020 Rock Lei Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package compress
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+)
+
+const (
+	// maximum length of block accepted by Reader, guards against allocation of corrupted length
+	maxBlockSize = 1 << 30
+
+	// payload is read by chunks, a corrupted size fails at the end of stream before it is allocated
+	readChunk = 1 << 20
+)
+
+// Reader decompresses data written by Writer
+type Reader struct {
+	r      *bufio.Reader
+	buf    []byte
+	sum    uint32
+	header bool
+	err    error
+}
+
+// NewReader returns Reader decompressing r
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r)}
+}
+
+// Read decompresses into p, returns io.EOF at the end of stream
+func (z *Reader) Read(p []byte) (int, error) {
+	for len(z.buf) == 0 && z.err == nil {
+		z.buf, z.err = z.next()
+	}
+	if len(z.buf) == 0 {
+		return 0, z.err
+	}
+
+	n := copy(p, z.buf)
+	z.buf = z.buf[n:]
+
+	return n, nil
+}
+
+// next reads and decompresses the next block
+func (z *Reader) next() ([]byte, error) {
+	if !z.header {
+		var hdr [4]byte
+		if _, err := io.ReadFull(z.r, hdr[:]); err != nil {
+			return nil, unexpected(err)
+		}
+		if !bytes.Equal(hdr[:3], magic) || hdr[3] != version {
+			return nil, ErrFormat
+		}
+		z.header = true
+	}
+
+	n, err := binary.ReadUvarint(z.r)
+	if err != nil {
+		return nil, unexpected(err)
+	} else if n == 0 {
+		var sum [4]byte
+		if _, err := io.ReadFull(z.r, sum[:]); err != nil {
+			return nil, unexpected(err)
+		} else if binary.BigEndian.Uint32(sum[:]) != z.sum {
+			return nil, ErrChecksum
+		}
+		return nil, io.EOF
+	}
+	size, err := binary.ReadUvarint(z.r)
+	if err != nil {
+		return nil, unexpected(err)
+	} else if n > maxBlockSize || size > 2*maxBlockSize {
+		return nil, ErrFormat
+	}
+
+	payload, err := readChunks(z.r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := decodeBlock(payload, int(n))
+	if err == nil {
+		z.sum = crc32.Update(z.sum, crc32.IEEETable, data)
+	}
+
+	return data, err
+}
+
+// readChunks reads n bytes by chunks, see sa decoder.bytes
+func readChunks(r io.Reader, n uint64) ([]byte, error) {
+	var p []byte
+	for n > 0 {
+		k := n
+		if k > readChunk {
+			k = readChunk
+		}
+		p = append(p, make([]byte, k)...)
+		if _, err := io.ReadFull(r, p[uint64(len(p))-k:]); err != nil {
+			return nil, unexpected(err)
+		}
+		n -= k
+	}
+
+	return p, nil
+}
+
+// unexpected converts io.EOF in the middle of stream
+func unexpected(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
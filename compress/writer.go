@@ -0,0 +1,157 @@
+/*
+ * Copyright 2020 Rock Lei Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package compress implements a block-sorting compressor. Every block of input is transformed by BWT,
+// then move-to-front, zero-run-length coding and Huffman coding, decompression restores blocks by
+// inverse BWT.
+//
+// Stream format: magic "SAZ", version, then blocks. Every block is the uvarint length of the
+// uncompressed data, the uvarint length of the payload and the payload, a block of length 0 ends
+// the stream, followed by crc32 of all uncompressed data.
+package compress
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+)
+
+const (
+	// DefaultBlockSize size of uncompressed block
+	DefaultBlockSize = 900 * 1024
+
+	version = 1
+)
+
+var (
+	magic = []byte("SAZ")
+
+	// ErrFormat input is not a valid compressed stream
+	ErrFormat = errors.New("compress: invalid format")
+
+	// ErrChecksum checksum of a block does not match
+	ErrChecksum = errors.New("compress: checksum error")
+
+	errClosed = errors.New("compress: write to closed writer")
+)
+
+// Writer compresses data written to it, Close must be called to flush the last block
+type Writer struct {
+	w         io.Writer
+	blockSize int
+	buf       []byte
+	sum       uint32
+	pipe      *pipeline
+	header    bool
+	closed    bool
+	err       error
+}
+
+// NewWriter returns Writer with DefaultBlockSize
+func NewWriter(w io.Writer) *Writer {
+	return NewWriterSize(w, DefaultBlockSize)
+}
+
+// NewWriterSize returns Writer compressing blocks of blockSize bytes
+func NewWriterSize(w io.Writer, blockSize int) *Writer {
+	return NewWriterWorkers(w, blockSize, 1)
+}
+
+// NewWriterWorkers returns Writer compressing up to workers blocks of blockSize bytes concurrently.
+// Blocks are written in order, the output is identical to NewWriterSize, memory is bounded by
+// workers + 1 blocks.
+func NewWriterWorkers(w io.Writer, blockSize, workers int) *Writer {
+	if blockSize < 1 {
+		panic("compress: block size must be positive")
+	}
+	return &Writer{w: w, blockSize: blockSize, pipe: newPipeline(workers)}
+}
+
+// Write compresses p, a block is written when it is full
+func (z *Writer) Write(p []byte) (int, error) {
+	if z.closed {
+		return 0, errClosed
+	}
+
+	n := 0
+	for len(p) > 0 && z.err == nil {
+		k := z.blockSize - len(z.buf)
+		if k > len(p) {
+			k = len(p)
+		}
+		z.buf, p, n = append(z.buf, p[:k]...), p[k:], n+k
+		if len(z.buf) == z.blockSize {
+			z.flush()
+		}
+	}
+
+	return n, z.err
+}
+
+// Close writes the last block and the end of stream, it does not close the underlying writer
+func (z *Writer) Close() error {
+	if z.closed {
+		return z.err
+	}
+	z.closed = true
+
+	if len(z.buf) > 0 {
+		z.flush()
+	}
+	z.pipe.drain(z.emit)
+	z.writeHeader()
+	var sum [4]byte
+	binary.BigEndian.PutUint32(sum[:], z.sum)
+	z.write(append(appendUvarint(nil, 0), sum[:]...))
+
+	return z.err
+}
+
+// flush submits the buffered block to compress
+func (z *Writer) flush() {
+	data := z.buf
+	z.sum = crc32.Update(z.sum, crc32.IEEETable, data)
+	z.pipe.submit(func() *bitWriter {
+		payload, w := getBitWriter(), getBitWriter()
+		encodeBlock(payload, data)
+		w.buf = appendUvarint(appendUvarint(w.buf, uint64(len(data))), uint64(len(payload.buf)))
+		w.buf = append(w.buf, payload.buf...)
+		payload.release()
+		return w
+	}, z.emit)
+	z.buf = nil
+}
+
+// emit writes a compressed block
+func (z *Writer) emit(b *bitWriter) {
+	z.writeHeader()
+	z.write(b.buf)
+	b.release()
+}
+
+func (z *Writer) writeHeader() {
+	if !z.header {
+		z.header = true
+		z.write(append(append([]byte{}, magic...), version))
+	}
+}
+
+func (z *Writer) write(p []byte) {
+	if z.err == nil {
+		_, z.err = z.w.Write(p)
+	}
+}
@@ -0,0 +1,60 @@
+/*
+ * Copyright 2020 Rock Lei Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sa
+
+// SuffixArrayDA returns the suffix array of t and its document array,
+// da[i] is the index of the string divided by separators that contains sa[i].
+// Note: separator belongs to the string it ends.
+//
+// DA is computed after sorting by the rank of separator positions, not induced along with the suffix array:
+// inducing DA carries the document of every induced suffix through each scan, which needs n more words
+// and would slow down the induction scans of every caller, including the parallel blocks of Options.Workers.
+// The rank pass is linear, it needs no memory but the da array and n bits.
+func SuffixArrayDA(t []byte) ([]int, []int) {
+	return (*Options)(nil).SuffixArrayDA(t)
+}
+
+// SuffixArrayDA returns the suffix array and document array with options, strings are divided by
+// Separator, t is one string if Binary or Standard, see SuffixArrayDA
+func (o *Options) SuffixArrayDA(t []byte) ([]int, []int) {
+	sa := o.SuffixArray(t)
+	return sa, o.DocumentArray(t, sa)
+}
+
+// DocumentArray returns the document array of t and its suffix array sa, in linear time by the rank of
+// separator positions
+func DocumentArray(t []byte, sa []int) []int {
+	return (*Options)(nil).DocumentArray(t, sa)
+}
+
+// DocumentArray returns the document array of t and its suffix array sa with options, see SuffixArrayDA
+func (o *Options) DocumentArray(t []byte, sa []int) []int {
+	// separators are sorted at the start of sa by their positions, marks them in a bit vector
+	seps, sep := newBitvec(len(t)), o.separator()
+	for i := 0; i < len(sa) && o.divided() && t[sa[i]] == sep; i++ {
+		seps.set(sa[i])
+	}
+	seps.build()
+
+	// sa[i] belongs to the string k, where k is the number of separators before sa[i]
+	da := make([]int, len(sa))
+	for i, p := range sa {
+		da[i] = seps.rank1(p)
+	}
+
+	return da
+}
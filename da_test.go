@@ -0,0 +1,59 @@
+package sa
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestSuffixArrayDA(t *testing.T) {
+	tests := []struct {
+		name   string
+		t      []byte
+		wantSA []int
+		wantDA []int
+	}{
+		{"empty", []byte{}, []int{}, []int{}},
+		{"one", []byte("abc"), []int{0, 1, 2}, []int{0, 0, 0}},
+		{"separators", toByte("aba$aba", '$', 1), []int{3, 0, 4, 2, 6, 1, 5}, []int{0, 0, 1, 0, 1, 0, 1}},
+		{"three", toByte("b$a$b", '$', 1), []int{1, 3, 2, 0, 4}, []int{0, 1, 1, 0, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sa, da := SuffixArrayDA(tt.t)
+			if !reflect.DeepEqual(sa, tt.wantSA) {
+				t.Errorf("SuffixArrayDA() sa = %v, want %v", sa, tt.wantSA)
+			}
+			if !reflect.DeepEqual(da, tt.wantDA) {
+				t.Errorf("SuffixArrayDA() da = %v, want %v", da, tt.wantDA)
+			}
+		})
+	}
+
+	r := rand.New(rand.NewSource(6))
+	for i := 0; i < 1000; i++ {
+		b := randText(r, 2+r.Intn(60), 1+r.Intn(4), true)
+		sa, da := SuffixArrayDA(b)
+		for j, p := range sa {
+			d := 0
+			for _, c := range b[:p] {
+				if c == separator {
+					d++
+				}
+			}
+			if da[j] != d {
+				t.Fatalf("SuffixArrayDA(%q) = %v, %v, want %d at %d", toString(b, 1, '$'), sa, da, d, j)
+			}
+		}
+
+		// same strings divided by '\n', one string if Binary
+		lines := []byte(toString(append([]byte{}, b...), 1, '\n'))
+		if lsa, lda := (&Options{Separator: '\n'}).SuffixArrayDA(lines); !reflect.DeepEqual(lsa, sa) ||
+			!reflect.DeepEqual(lda, da) {
+			t.Fatalf("SuffixArrayDA(%q) of '\\n' = %v, %v, want %v, %v", lines, lsa, lda, sa, da)
+		}
+		if _, bda := (&Options{Binary: true}).SuffixArrayDA(b); !reflect.DeepEqual(bda, make([]int, len(b))) {
+			t.Fatalf("SuffixArrayDA(%q) of Binary = %v, want zeros", toString(b, 1, '$'), bda)
+		}
+	}
+}
@@ -0,0 +1,271 @@
+/*
+ * Copyright 2020 Rock Lei Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sa
+
+const (
+	// number of BWT bytes per block of occurrence counters
+	occBlock = 256
+
+	// number of blocks per super block, counters of a block are relative to its super block,
+	// so they fit uint32 for texts larger than 2^32
+	occSuper = 1 << 16
+)
+
+// Ranker rank over the rows of BWT
+type Ranker interface {
+	// Len returns the number of rows
+	Len() int
+
+	// Access returns the byte of row i
+	Access(i int) byte
+
+	// Rank returns the number of byte c before row i
+	Rank(c byte, i int) int
+}
+
+// FMIndex backward search over BWT
+type FMIndex struct {
+	rank Ranker
+
+	// dict of the bytes of BWT from Aux, code[c] is the index of byte c in dict, -1 if c is not in BWT,
+	// index (0) is sentinel and (1) is separator
+	dict []byte
+	code [alphabetSize]int
+
+	// c[k] number of bytes in BWT less than dict[k], ie, start row of bucket dict[k]
+	c []int
+
+	// end row of the end of text of binary BWT, its byte (0) is not a symbol, -1 if not binary
+	end int
+
+	// ssa sampled suffix array for Locate
+	ssa *sampledSA
+}
+
+// occTable occurrence counters of every block of BWT
+type occTable struct {
+	bwt  []byte
+	dict []byte
+	code *[alphabetSize]int
+
+	// occ[b*len(dict)+k] number of dict[k] in BWT before block b, since the start of its super block
+	occ []uint32
+
+	// super[s*len(dict)+k] number of dict[k] in BWT before super block s
+	super []int
+}
+
+// NewFMIndex creates FMIndex of bwt with occurrence counters, bwt and aux are returned by BWT
+func NewFMIndex(bwt []byte, aux *Aux) *FMIndex {
+	if aux.Len != uint(len(bwt)) {
+		panic("sa: Aux does not match BWT")
+	}
+
+	f := newFMIndex(aux)
+	f.rank = newOccTable(bwt, f.dict, &f.code)
+	return f
+}
+
+// NewFMIndexRanker creates FMIndex of BWT ranked by r, eg, WaveletMatrix, aux is returned by BWT
+func NewFMIndexRanker(r Ranker, aux *Aux) *FMIndex {
+	if aux.Len != uint(r.Len()) {
+		panic("sa: Aux does not match BWT")
+	}
+
+	f := newFMIndex(aux)
+	f.rank = r
+	return f
+}
+
+func newFMIndex(aux *Aux) *FMIndex {
+	f := &FMIndex{dict: aux.symbols(), end: -1}
+	if aux.Binary {
+		f.end = int(aux.End)
+	}
+
+	// note: symbols of binary BWT override (0) and (1) of sentinel and separator
+	for i := range f.code {
+		f.code[i] = -1
+	}
+	for k, c := range f.dict {
+		f.code[c] = k
+	}
+
+	hist := aux.hist()
+	f.c = make([]int, len(f.dict))
+	for k, sum := 0, 0; k < len(f.dict); k++ {
+		f.c[k] = sum
+		switch {
+		case k == 0:
+			// sentinel
+			sum++
+		case k > separator || !aux.Binary:
+			sum += hist[aux.Dict[k]]
+		}
+	}
+
+	return f
+}
+
+func newOccTable(bwt, dict []byte, code *[alphabetSize]int) *occTable {
+	sz, blocks := len(dict), len(bwt)/occBlock+1
+	o := &occTable{bwt, dict, code, make([]uint32, 0, blocks*sz), make([]int, 0, (blocks/occSuper+1)*sz)}
+	cnt, base := make([]int, sz), make([]int, sz)
+	for b := 0; b < blocks; b++ {
+		if b%occSuper == 0 {
+			copy(base, cnt)
+			o.super = append(o.super, base...)
+		}
+		for k := range cnt {
+			o.occ = append(o.occ, uint32(cnt[k]-base[k]))
+		}
+
+		end := (b + 1) * occBlock
+		if end > len(bwt) {
+			end = len(bwt)
+		}
+		for _, c := range bwt[b*occBlock : end] {
+			cnt[code[c]]++
+		}
+	}
+
+	return o
+}
+
+func (o *occTable) Len() int {
+	return len(o.bwt)
+}
+
+func (o *occTable) Access(i int) byte {
+	return o.bwt[i]
+}
+
+func (o *occTable) Rank(c byte, i int) int {
+	k := o.code[c]
+	if k < 0 {
+		return 0
+	}
+
+	// count from the closer block boundary
+	b := i / occBlock
+	if i%occBlock > occBlock/2 && (b+1)*occBlock <= len(o.bwt) {
+		r := o.count(b+1, k)
+		for _, x := range o.bwt[i : (b+1)*occBlock] {
+			if x == c {
+				r--
+			}
+		}
+		return r
+	}
+
+	r := o.count(b, k)
+	for _, x := range o.bwt[b*occBlock : i] {
+		if x == c {
+			r++
+		}
+	}
+	return r
+}
+
+// count returns the number of dict[k] before block b
+func (o *occTable) count(b, k int) int {
+	sz := len(o.dict)
+	return o.super[b/occSuper*sz+k] + int(o.occ[b*sz+k])
+}
+
+// Len returns the length of the text
+func (f *FMIndex) Len() int {
+	return f.rank.Len() - 1
+}
+
+// Rank returns the number of byte c in BWT before row i, the end of text of binary BWT is not counted
+func (f *FMIndex) Rank(c byte, i int) int {
+	r := f.rank.Rank(c, i)
+	if c == 0 && f.end >= 0 && f.end < i {
+		r--
+	}
+	return r
+}
+
+// Text restores the text of BWT by LF mapping
+func (f *FMIndex) Text() []byte {
+	t := make([]byte, 0, f.Len())
+	f.walk(func(c byte, i int) {
+		t = append(t, c)
+	})
+	return t
+}
+
+// walk walks the text from sentinel by LF mapping, fn is called with the byte and the row of every
+// position in the order of positions
+func (f *FMIndex) walk(fn func(c byte, i int)) {
+	// separators are ordered by their positions, see InverseBWT
+	sep := f.c[separator]
+
+	// note: f.end is never reached unless binary
+	for i := 0; i != f.end; {
+		c := f.rank.Access(i)
+		k := f.code[c]
+		if f.end < 0 && k == 0 {
+			return
+		}
+		if f.end < 0 && k == separator {
+			i = sep
+			sep++
+		} else {
+			i = f.lf(c, i)
+		}
+		fn(c, i)
+	}
+}
+
+// lf returns the row of the position next to row i, c is the byte of row i
+func (f *FMIndex) lf(c byte, i int) int {
+	return f.c[f.code[c]] + f.Rank(c, i)
+}
+
+// Count returns the number of occurrences of pattern
+func (f *FMIndex) Count(pattern []byte) int {
+	lo, hi := f.Interval(pattern)
+	return hi - lo
+}
+
+// Interval returns the interval of suffix array [lo, hi), where pattern ends at positions sa[lo:hi].
+// Note: suffix array is sorted backwards, see SuffixArray, pattern is searched from its first byte.
+func (f *FMIndex) Interval(pattern []byte) (int, int) {
+	if len(pattern) == 0 {
+		return 0, f.Len()
+	}
+
+	// rows of BWT, row 0 is sentinel, row i + 1 is sa[i]
+	lo, hi := 0, f.rank.Len()
+	for _, c := range pattern {
+		// note: sentinel and separator, or bytes not in BWT, k is the symbol index of binary BWT
+		k := f.code[c]
+		if k <= separator {
+			return 0, 0
+		}
+
+		// LF mapping, rows of bucket c followed by rows in [lo, hi) with BWT c
+		lo, hi = f.c[k]+f.Rank(c, lo), f.c[k]+f.Rank(c, hi)
+		if lo >= hi {
+			return 0, 0
+		}
+	}
+
+	return lo - 1, hi - 1
+}
@@ -0,0 +1,290 @@
+package sa
+
+import (
+	"bytes"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// naiveEnds returns the sorted positions of the last byte of pattern in t
+func naiveEnds(t, pattern []byte) []int {
+	ends := []int{}
+	for i := 0; i+len(pattern) <= len(t); i++ {
+		if bytes.Equal(t[i:i+len(pattern)], pattern) {
+			ends = append(ends, i+len(pattern)-1)
+		}
+	}
+
+	return ends
+}
+
+func TestFMIndex(t *testing.T) {
+	r := rand.New(rand.NewSource(8))
+	for i := 0; i < 300; i++ {
+		text := randText(r, 2+r.Intn(1200), 1+r.Intn(4), i%2 == 0)
+		sa := SuffixArray(text)
+		_, bwt, aux := BWT(append([]byte{}, text...))
+		f := NewFMIndex(bwt, aux)
+
+		if f.Len() != len(text) {
+			t.Fatalf("Len() = %d, want %d", f.Len(), len(text))
+		}
+		for c := 0; c < alphabetSize; c++ {
+			for _, j := range []int{0, r.Intn(len(bwt) + 1), len(bwt)} {
+				if got, want := f.Rank(byte(c), j), bytes.Count(bwt[:j], []byte{byte(c)}); got != want {
+					t.Fatalf("Rank(%d, %d) = %d, want %d", c, j, got, want)
+				}
+			}
+		}
+
+		for j := 0; j < 20; j++ {
+			p := randText(r, 1+r.Intn(4), 1+r.Intn(4), false)
+			want := naiveEnds(text, p)
+			if got := f.Count(p); got != len(want) {
+				t.Fatalf("Count(%q) = %d, want %d", p, got, len(want))
+			}
+
+			lo, hi := f.Interval(p)
+			got := append([]int{}, sa[lo:hi]...)
+			sort.Ints(got)
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("Interval(%q) = %v, want %v", p, got, want)
+			}
+		}
+	}
+}
+
+func TestFMIndexPattern(t *testing.T) {
+	text := toByte("sisisim$sisisim$anana", '$', 1)
+	_, bwt, aux := BWT(append([]byte{}, text...))
+	f := NewFMIndex(bwt, aux)
+
+	tests := []struct {
+		pattern string
+		want    int
+	}{
+		{"", 21},
+		{"si", 6},
+		{"sim", 2},
+		{"ana", 2},
+		{"m$s", 0},
+		{"x", 0},
+		{"\x00", 0},
+	}
+	for _, tt := range tests {
+		if got := f.Count(toByte(tt.pattern, '$', 1)); got != tt.want {
+			t.Errorf("Count(%q) = %d, want %d", tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestOccTableSuper(t *testing.T) {
+	// more than two super blocks, counters of each block are relative to its super block
+	r := rand.New(rand.NewSource(19))
+	bwt := make([]byte, 2*occSuper*occBlock+1000)
+	dict := []byte{0, 1, 'a', 'c', 'g', 't'}
+	for i := range bwt {
+		bwt[i] = dict[2+r.Intn(4)]
+	}
+	var code [alphabetSize]int
+	for k, c := range dict {
+		code[c] = k
+	}
+	o := newOccTable(bwt, dict, &code)
+
+	positions := []int{0, len(bwt), occSuper * occBlock, occSuper*occBlock - 1, occSuper*occBlock + 200}
+	for i := 0; i < 30; i++ {
+		positions = append(positions, r.Intn(len(bwt)+1))
+	}
+	for _, i := range positions {
+		c := dict[2+r.Intn(4)]
+		if got, want := o.Rank(c, i), bytes.Count(bwt[:i], []byte{c}); got != want {
+			t.Fatalf("Rank(%c, %d) = %d, want %d", c, i, got, want)
+		}
+	}
+}
+
+func TestFMIndexBinary(t *testing.T) {
+	o := &Options{Binary: true}
+	r := rand.New(rand.NewSource(26))
+	for i := 0; i < 300; i++ {
+		// mostly sentinel and separator bytes
+		text := make([]byte, r.Intn(300))
+		for j := range text {
+			text[j] = byte(r.Intn(3))
+			if r.Intn(4) == 0 {
+				text[j] = byte(r.Intn(256))
+			}
+		}
+		l, bwt, aux := o.BWT(append([]byte{}, text...))
+		f := NewFMIndex(bwt, aux)
+		f.Sample(1 + r.Intn(10))
+		w := NewFMIndexRanker(NewWaveletMatrix(bwt, aux), aux)
+		w.Sample(1 + r.Intn(10))
+
+		var buf bytes.Buffer
+		if _, err := (&Index{l, bwt, aux}).WriteTo(&buf); err != nil {
+			t.Fatal(err)
+		}
+		var x Index
+		if _, err := x.ReadFrom(&buf); err != nil {
+			t.Fatal(err)
+		}
+		if got := x.Text(); !bytes.Equal(got, text) {
+			t.Fatalf("Text() of ReadFrom = %v, want %v", got, text)
+		}
+		g := NewFMIndex(x.BWT, x.Aux)
+		if err := f.WriteMapped(&buf); err != nil {
+			t.Fatal(err)
+		}
+		m, err := NewMappedIndex(buf.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if f.Len() != len(text) || m.Len() != len(text) {
+			t.Fatalf("Len() = %d, %d, want %d", f.Len(), m.Len(), len(text))
+		}
+		if got := f.Text(); !bytes.Equal(got, text) {
+			t.Fatalf("Text() = %v, want %v", got, text)
+		}
+		if got := w.Text(); !bytes.Equal(got, text) {
+			t.Fatalf("Text() of wavelet = %v, want %v", got, text)
+		}
+		for _, c := range []byte{0, 1, byte(r.Intn(256))} {
+			j := r.Intn(len(bwt) + 1)
+			want := bytes.Count(bwt[:j], []byte{c})
+			if c == 0 && l < j {
+				want--
+			}
+			if got := f.Rank(c, j); got != want {
+				t.Fatalf("Rank(%d, %d) = %d, want %d", c, j, got, want)
+			}
+		}
+
+		for j := 0; j < 20; j++ {
+			p := make([]byte, 1+r.Intn(4))
+			for k := range p {
+				p[k] = byte(r.Intn(3))
+			}
+			if len(text) > 0 && r.Intn(2) == 0 {
+				s := r.Intn(len(text))
+				p = text[s : s+1+r.Intn(len(text)-s)]
+			}
+			want := []int{}
+			for _, e := range naiveEnds(text, p) {
+				want = append(want, e-len(p)+1)
+			}
+
+			if got := f.Count(p); got != len(want) {
+				t.Fatalf("Count(%v) = %d, want %d", p, got, len(want))
+			}
+			if got := f.Locate(p); !reflect.DeepEqual(got, want) {
+				t.Fatalf("Locate(%v) = %v, want %v", p, got, want)
+			}
+			if got := w.Locate(p); !reflect.DeepEqual(got, want) {
+				t.Fatalf("Locate(%v) of wavelet = %v, want %v", p, got, want)
+			}
+			if got := g.Count(p); got != len(want) {
+				t.Fatalf("Count(%v) of ReadFrom = %d, want %d", p, got, len(want))
+			}
+			if got, err := m.Locate(p); err != nil || !reflect.DeepEqual(got, want) {
+				t.Fatalf("Locate(%v) of mapped = %v, %v, want %v", p, got, err, want)
+			}
+		}
+	}
+}
+
+func TestFMIndexSeparator(t *testing.T) {
+	o := &Options{Separator: '\n', Sentinel: '#'}
+	r := rand.New(rand.NewSource(27))
+	for i := 0; i < 200; i++ {
+		// lines of 'a' to 'c' and (1), which is not a separator
+		texts := make([][]byte, 2)
+		for k := range texts {
+			texts[k] = make([]byte, 1+r.Intn(200))
+			for j := range texts[k] {
+				texts[k][j] = []byte("abc\x01\n")[r.Intn(5)]
+			}
+		}
+		text := append(append(append([]byte{}, texts[0]...), '\n'), texts[1]...)
+		offsets := []int{0}
+		for j, c := range text {
+			if c == '\n' {
+				offsets = append(offsets, j+1)
+			}
+		}
+
+		l, bwt, aux := o.BWT(append([]byte{}, text...))
+		f := NewFMIndex(bwt, aux)
+		f.Sample(1 + r.Intn(10))
+		w := NewFMIndexRanker(NewWaveletMatrix(bwt, aux), aux)
+		x := NewRIndex(bwt, aux)
+
+		// merged BWT of both texts is the BWT of the text
+		_, a, auxA := o.BWT(append([]byte{}, texts[0]...))
+		_, b, auxB := o.BWT(append([]byte{}, texts[1]...))
+		if mb, maux := MergeBWT(a, b, auxA, auxB); !bytes.Equal(mb, bwt) || !reflect.DeepEqual(maux, aux) {
+			t.Fatalf("MergeBWT() = %q, want %q", mb, bwt)
+		}
+
+		var buf bytes.Buffer
+		if _, err := (&Index{l, bwt, aux}).WriteTo(&buf); err != nil {
+			t.Fatal(err)
+		}
+		var y Index
+		if _, err := y.ReadFrom(&buf); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(y.Aux, aux) {
+			t.Fatalf("ReadFrom() Aux = %v, want %v", y.Aux, aux)
+		}
+		if err := f.WriteMapped(&buf); err != nil {
+			t.Fatal(err)
+		}
+		m, err := NewMappedIndex(buf.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := f.Text(); !bytes.Equal(got, text) {
+			t.Fatalf("Text() = %q, want %q", got, text)
+		}
+		if got := w.Text(); !bytes.Equal(got, text) {
+			t.Fatalf("Text() of wavelet = %q, want %q", got, text)
+		}
+		for j := 0; j < 20; j++ {
+			p := make([]byte, 1+r.Intn(4))
+			for k := range p {
+				p[k] = []byte("abc\x01")[r.Intn(4)]
+			}
+			want, wantDocs := []int{}, []Match{}
+			for _, e := range naiveEnds(text, p) {
+				s := e - len(p) + 1
+				d := sort.SearchInts(offsets, s+1) - 1
+				want = append(want, s)
+				wantDocs = append(wantDocs, Match{d, s - offsets[d]})
+			}
+
+			if got := f.LocateDocs(p); !reflect.DeepEqual(got, wantDocs) {
+				t.Fatalf("LocateDocs(%q) = %v, want %v", p, got, wantDocs)
+			}
+			if got := w.Count(p); got != len(want) {
+				t.Fatalf("Count(%q) of wavelet = %d, want %d", p, got, len(want))
+			}
+			if got := x.LocateDocs(p); !reflect.DeepEqual(got, wantDocs) {
+				t.Fatalf("LocateDocs(%q) of RIndex = %v, want %v", p, got, wantDocs)
+			}
+			if got, err := m.Locate(p); err != nil || !reflect.DeepEqual(got, want) {
+				t.Fatalf("Locate(%q) of mapped = %v, %v, want %v", p, got, err, want)
+			}
+		}
+		for _, p := range []string{"\n", "a\n", "#", "a#"} {
+			if f.Count([]byte(p)) != 0 || x.Count([]byte(p)) != 0 || m.Count([]byte(p)) != 0 {
+				t.Fatalf("Count(%q) = %d, want 0", p, f.Count([]byte(p)))
+			}
+		}
+	}
+}
@@ -0,0 +1,270 @@
+//go:build ignore
+// +build ignore
+
+/*
+ * Copyright 2020 Rock Lei Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// gen32 generates is32.go, int32 suffix array variant of the induced sorting in is.go.
+// Functions taking sa []int are copied with suffix 32, sa becomes []int32, values read from sa are
+// converted to int and values written to sa are converted to int32, all other arithmetic stays int.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"log"
+	"strings"
+)
+
+const header = `// Code generated by gen32.go; DO NOT EDIT.
+
+package sa
+`
+
+func main() {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "is.go", nil, 0)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// unexported functions with parameter sa []int
+	funcs := map[string]*ast.FuncDecl{}
+	for _, d := range f.Decls {
+		if fn, ok := d.(*ast.FuncDecl); ok && fn.Recv == nil && !fn.Name.IsExported() && hasSA(fn) {
+			funcs[fn.Name.Name] = fn
+		}
+	}
+
+	var body bytes.Buffer
+	used := map[string]bool{}
+	for _, d := range f.Decls {
+		fn, ok := d.(*ast.FuncDecl)
+		if !ok || funcs[fn.Name.Name] == nil {
+			continue
+		}
+		fn.Doc = nil
+		rewrite(fn, funcs)
+		ast.Inspect(fn.Body, func(n ast.Node) bool {
+			if x, ok := n.(*ast.SelectorExpr); ok {
+				if id, ok := x.X.(*ast.Ident); ok {
+					used[id.Name] = true
+				}
+			}
+			return true
+		})
+		if err := format.Node(&body, token.NewFileSet(), fn); err != nil {
+			log.Fatal(err)
+		}
+		body.WriteString("\n\n")
+	}
+
+	// packages of is.go used by the generated functions
+	var out bytes.Buffer
+	out.WriteString(header)
+	for _, im := range f.Imports {
+		if path := strings.Trim(im.Path.Value, `"`); used[path[strings.LastIndex(path, "/")+1:]] {
+			fmt.Fprintf(&out, "\nimport %s\n", im.Path.Value)
+		}
+	}
+	out.WriteString("\n")
+	out.Write(body.Bytes())
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := ioutil.WriteFile("is32.go", src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func hasSA(fn *ast.FuncDecl) bool {
+	for _, p := range fn.Type.Params.List {
+		for _, n := range p.Names {
+			if n.Name == "sa" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func rewrite(fn *ast.FuncDecl, funcs map[string]*ast.FuncDecl) {
+	fn.Name.Name += "32"
+
+	// parameter sa []int, shared with bkt and hist in the same field
+	var params []*ast.Field
+	for _, p := range fn.Type.Params.List {
+		var names, sa []*ast.Ident
+		for _, n := range p.Names {
+			if n.Name == "sa" {
+				sa = append(sa, n)
+			} else {
+				names = append(names, n)
+			}
+		}
+		if len(sa) > 0 {
+			params = append(params, &ast.Field{Names: sa, Type: &ast.ArrayType{Elt: ast.NewIdent("int32")}})
+		}
+		if len(names) > 0 {
+			params = append(params, &ast.Field{Names: names, Type: p.Type})
+		}
+	}
+	fn.Type.Params.List = params
+
+	fn.Body = rewriteStmt(fn.Body, funcs).(*ast.BlockStmt)
+}
+
+func isSA(e ast.Expr) bool {
+	x, ok := e.(*ast.IndexExpr)
+	if !ok {
+		return false
+	}
+	id, ok := x.X.(*ast.Ident)
+	return ok && id.Name == "sa"
+}
+
+func call(name string, e ast.Expr) ast.Expr {
+	return &ast.CallExpr{Fun: ast.NewIdent(name), Args: []ast.Expr{e}}
+}
+
+func rewriteStmt(s ast.Stmt, funcs map[string]*ast.FuncDecl) ast.Stmt {
+	switch s := s.(type) {
+	case *ast.BlockStmt:
+		for i, x := range s.List {
+			s.List[i] = rewriteStmt(x, funcs)
+		}
+	case *ast.AssignStmt:
+		if len(s.Lhs) != len(s.Rhs) {
+			// values of a call, never assigned to sa
+			s.Rhs[0] = rewriteExpr(s.Rhs[0], funcs)
+			break
+		}
+		for i, l := range s.Lhs {
+			if isSA(l) {
+				x := l.(*ast.IndexExpr)
+				x.Index = rewriteExpr(x.Index, funcs)
+				s.Rhs[i] = call("int32", rewriteExpr(s.Rhs[i], funcs))
+			} else {
+				s.Lhs[i] = rewriteExpr(l, funcs)
+				s.Rhs[i] = rewriteExpr(s.Rhs[i], funcs)
+			}
+		}
+	case *ast.IncDecStmt:
+		if isSA(s.X) {
+			x := s.X.(*ast.IndexExpr)
+			x.Index = rewriteExpr(x.Index, funcs)
+		} else {
+			s.X = rewriteExpr(s.X, funcs)
+		}
+	case *ast.ExprStmt:
+		s.X = rewriteExpr(s.X, funcs)
+	case *ast.ReturnStmt:
+		for i, r := range s.Results {
+			s.Results[i] = rewriteExpr(r, funcs)
+		}
+	case *ast.IfStmt:
+		if s.Init != nil {
+			s.Init = rewriteStmt(s.Init, funcs)
+		}
+		s.Cond = rewriteExpr(s.Cond, funcs)
+		rewriteStmt(s.Body, funcs)
+		if s.Else != nil {
+			s.Else = rewriteStmt(s.Else, funcs)
+		}
+	case *ast.ForStmt:
+		if s.Init != nil {
+			s.Init = rewriteStmt(s.Init, funcs)
+		}
+		if s.Cond != nil {
+			s.Cond = rewriteExpr(s.Cond, funcs)
+		}
+		if s.Post != nil {
+			s.Post = rewriteStmt(s.Post, funcs)
+		}
+		rewriteStmt(s.Body, funcs)
+	case *ast.RangeStmt:
+		rewriteStmt(s.Body, funcs)
+		// for i, s := range sa -> for i, s32 := range sa { s := int(s32)
+		if id, ok := s.X.(*ast.Ident); ok && id.Name == "sa" && s.Value != nil {
+			v := s.Value.(*ast.Ident)
+			s.Value = ast.NewIdent(v.Name + "32")
+			def := &ast.AssignStmt{Lhs: []ast.Expr{ast.NewIdent(v.Name)}, Tok: token.DEFINE, Rhs: []ast.Expr{call("int", s.Value)}}
+			s.Body.List = append([]ast.Stmt{def}, s.Body.List...)
+		} else {
+			s.X = rewriteExpr(s.X, funcs)
+		}
+	case *ast.DeclStmt, *ast.BranchStmt, *ast.EmptyStmt:
+	default:
+		log.Fatalf("gen32: unsupported statement %T", s)
+	}
+
+	return s
+}
+
+func rewriteExpr(e ast.Expr, funcs map[string]*ast.FuncDecl) ast.Expr {
+	switch x := e.(type) {
+	case *ast.IndexExpr:
+		x.X = rewriteExpr(x.X, funcs)
+		x.Index = rewriteExpr(x.Index, funcs)
+		if isSA(x) {
+			return call("int", x)
+		}
+	case *ast.SliceExpr:
+		x.Low, x.High, x.Max = rewriteOpt(x.Low, funcs), rewriteOpt(x.High, funcs), rewriteOpt(x.Max, funcs)
+	case *ast.CallExpr:
+		if id, ok := x.Fun.(*ast.Ident); ok {
+			if funcs[id.Name] != nil {
+				x.Fun = ast.NewIdent(id.Name + "32")
+			} else if id.Name == "intbuf" {
+				x.Fun = ast.NewIdent("intbuf32")
+			}
+		} else {
+			x.Fun = rewriteExpr(x.Fun, funcs)
+		}
+		for i, a := range x.Args {
+			x.Args[i] = rewriteExpr(a, funcs)
+		}
+	case *ast.BinaryExpr:
+		x.X, x.Y = rewriteExpr(x.X, funcs), rewriteExpr(x.Y, funcs)
+	case *ast.UnaryExpr:
+		x.X = rewriteExpr(x.X, funcs)
+	case *ast.ParenExpr:
+		x.X = rewriteExpr(x.X, funcs)
+	case *ast.SelectorExpr:
+		x.X = rewriteExpr(x.X, funcs)
+	case *ast.FuncLit:
+		rewriteStmt(x.Body, funcs)
+	case *ast.Ident, *ast.BasicLit, *ast.ArrayType:
+	default:
+		log.Fatalf("gen32: unsupported expression %T", e)
+	}
+
+	return e
+}
+
+func rewriteOpt(e ast.Expr, funcs map[string]*ast.FuncDecl) ast.Expr {
+	if e == nil {
+		return nil
+	}
+	return rewriteExpr(e, funcs)
+}
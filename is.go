@@ -19,6 +19,10 @@
 
 package sa
 
+import "math"
+
+//go:generate go run gen32.go
+
 const (
 	alphabetSize = 256
 	separator    = 1
@@ -35,11 +39,21 @@ type Aux struct {
 	// Dist distribution
 	Dist []uint
 
-	// Hist histogram
+	// Hist histogram, (count<<8)|byte, a count larger than uint can hold is split into entries of the same byte
 	Hist []uint
 
 	// dictionary, [0] -> 0, [1] -> 1, [2] -> can be any char
 	Dict []byte
+
+	// Binary BWT of Options.Binary, End is the row of the end of text. Bytes of other rows are symbols
+	// shifted by 2 above sentinel and separator, Eob, Dist and Hist count them, Dict[2:] are the bytes
+	// of the symbols, which can be (0) and (1)
+	Binary bool
+	End    uint
+
+	// Separator and Sentinel of Options, BWT is of the original bytes, Eob, Hist and Dict are of the bytes
+	// translated by Options, ie, Sentinel as (0), Separator as (1), other bytes ascending from (2)
+	Separator, Sentinel byte
 }
 
 type buf interface {
@@ -50,6 +64,7 @@ type buf interface {
 
 type bytebuf []byte
 type intbuf []int
+type intbuf32 []int32
 
 // start bytebuf
 
@@ -83,6 +98,22 @@ func (b intbuf) eq(x, y int) bool {
 
 // end intbuf
 
+// start intbuf32
+
+func (b intbuf32) len() int {
+	return len(b)
+}
+
+func (b intbuf32) get(i int) int {
+	return int(b[i])
+}
+
+func (b intbuf32) eq(x, y int) bool {
+	return b[x] == b[y]
+}
+
+// end intbuf32
+
 func reset(chars []uint) {
 	chars[0] = 0
 	sz := 1
@@ -92,22 +123,331 @@ func reset(chars []uint) {
 	}
 }
 
-// BWT transforms t into BWT, returns the length of BWT, BWT and auxiliary data structure can be used to merge BWTs
+// Options options of suffix sorting, the zero value sorts sequentially
+type Options struct {
+	// Workers number of goroutines for induced sorting and naming of LMS substrings, less than 2 sorts
+	// sequentially. Results are identical for any number of workers, texts shorter than 1MB are sorted
+	// sequentially.
+	Workers int
+
+	// Binary t can contain any byte including (0) and (1), there is no separator, t is one string.
+	// Bytes are sorted as symbols above sentinel and separator, BWT returns the row of the end of text
+	// and Aux of the symbols, see Aux.Binary, BWT is restored by Options.InverseBWT.
+	Binary bool
+
+	// Separator byte value divides strings instead of (1), eg, '\n' for lines, zero value is (1).
+	// Sentinel byte value of the end of text in BWT instead of (0), t must not contain it.
+	// Other bytes keep their order after separators. If either is set, BWT returns Aux of the translated
+	// bytes, see Aux.Separator, FMIndex, WaveletMatrix, RIndex and MergeBWT translate through the same
+	// code, BWT is restored by Options.InverseBWT. Both are ignored if Binary.
+	Separator, Sentinel byte
+
+	// Standard sorts suffixes forward with sentinel at the end of text, the conventional suffix array
+	// and BWT as libdivsufsort and sais-lite. t can contain any byte, there is no separator. BWT returns
+	// the primary index, ie, the row of t among the sorted suffixes of t$ where row 0 is $, BWT of
+	// len(t) bytes without $ and nil Aux, BWT is restored by Options.InverseBWT. Binary, Separator
+	// and Sentinel are ignored if Standard.
+	Standard bool
+
+	// InPlace BWT overwrites t with BWT and returns the same array, t must have capacity for BWT,
+	// ie, len(t) + 1, or len(t) if Standard, BWT panics otherwise, the array is never reallocated.
+	// Empty t needs no capacity, there is nothing to overwrite, BWT of one byte is allocated.
+	// BWT copies t and leaves it untouched if not InPlace.
+	InPlace bool
+}
+
+func (o *Options) workers() int {
+	if o == nil || o.Workers < 1 {
+		return 1
+	}
+	return o.Workers
+}
+
+func (o *Options) binary() bool {
+	return o != nil && o.Binary && !o.Standard
+}
+
+func (o *Options) standard() bool {
+	return o != nil && o.Standard
+}
+
+func (o *Options) inPlace() bool {
+	return o != nil && o.InPlace
+}
+
+// divided returns whether t is strings divided by separator, ie, neither Binary nor Standard
+func (o *Options) divided() bool {
+	return o == nil || !o.Binary && !o.Standard
+}
+
+// separator returns Separator, (1) if it is not set
+func (o *Options) separator() byte {
+	if o == nil || o.Separator == 0 {
+		return separator
+	}
+	return o.Separator
+}
+
+// alphabet returns code of every byte if Separator or Sentinel is set, code[Sentinel] is (0),
+// code[Separator] is separator, other bytes are ascending from (2), returns nil if bytes are sorted as they are
+func (o *Options) alphabet() *[alphabetSize]byte {
+	if o == nil || o.Binary || o.Standard || (o.Separator == 0 || o.Separator == separator) && o.Sentinel == 0 {
+		return nil
+	}
+
+	sep := o.separator()
+	if sep == o.Sentinel {
+		panic("sa: separator and sentinel are the same byte")
+	}
+
+	code, k := new([alphabetSize]byte), byte(2)
+	code[o.Sentinel], code[sep] = 0, separator
+	for c := 0; c < alphabetSize; c++ {
+		if b := byte(c); b != o.Sentinel && b != sep {
+			code[c] = k
+			k++
+		}
+	}
+
+	return code
+}
+
+// decode returns the inverse of code
+func decode(code *[alphabetSize]byte) *[alphabetSize]byte {
+	dec := new([alphabetSize]byte)
+	for c, x := range code {
+		dec[x] = byte(c)
+	}
+	return dec
+}
+
+// code returns code of Options.Separator and Options.Sentinel of x, nil if bytes are not translated
+func (x *Aux) code() *[alphabetSize]byte {
+	return (&Options{Separator: x.Separator, Sentinel: x.Sentinel}).alphabet()
+}
+
+// symbols returns Dict of the original bytes of BWT, dict[0] is sentinel and dict[1] is separator
+func (x *Aux) symbols() []byte {
+	code := x.code()
+	if code == nil {
+		return x.Dict
+	}
+
+	dec, dict := decode(code), make([]byte, len(x.Dict))
+	for k, c := range x.Dict {
+		dict[k] = dec[c]
+	}
+	return dict
+}
+
+// translate replaces every byte of t by its code
+func translate(t []byte, code *[alphabetSize]byte) {
+	for i, c := range t {
+		t[i] = code[c]
+	}
+}
+
+// BWT transforms t into BWT, returns the row of the end of text, BWT and auxiliary data structure can be used
+// to merge BWTs. t is not modified, see Options.InPlace to reuse its array.
+// Suffix array of int32 is used if t is shorter than 2^31, which halves the memory.
 func BWT(t []byte) (int, []byte, *Aux) {
-	sa := make([]int, len(t))
-	// dict -> 0 -> 0, 1 -> 1, 2 -> '\n'
-	l, arr, dict := sais(bytebuf(t), sa, alphabetSize, true, false)
-	t = append(t, 1)
+	return (*Options)(nil).BWT(t)
+}
+
+// BWT transforms t into BWT with options, see BWT
+func (o *Options) BWT(t []byte) (int, []byte, *Aux) {
+	// BWT has one more byte of the end of text, except Standard
+	n := len(t) + 1
+	if o.standard() {
+		n = len(t)
+	}
+	if !o.inPlace() {
+		t = append(make([]byte, 0, n), t...)
+	} else if cap(t) < n && len(t) > 0 {
+		panic("sa: capacity of text is too small for BWT in place")
+	}
+
+	if o.standard() {
+		l, b := bwtStandard(t, len(t) > math.MaxInt32, o.workers())
+		return l, b, nil
+	}
+	if o.binary() {
+		l, b := bwtBinary(t, len(t) > math.MaxInt32, o.workers())
+		return l, b, newAuxBinary(b, l)
+	}
+	if code := o.alphabet(); code != nil {
+		// note: Aux is of the translated bytes
+		translate(t, code)
+		l, b, aux := bwt(t, len(t) > math.MaxInt32, o.workers())
+		translate(b, decode(code))
+		aux.Separator, aux.Sentinel = o.separator(), o.Sentinel
+		return l, b, aux
+	}
+	return bwt(t, len(t) > math.MaxInt32, o.workers())
+}
+
+// InverseBWT restores the text from bwt with options, see InverseBWT
+func (o *Options) InverseBWT(bwt []byte, l int) []byte {
+	if o.standard() {
+		return inverseStandard(bwt, l)
+	}
+	if o.binary() {
+		return inverseBinary(bwt, l)
+	}
+	if code := o.alphabet(); code != nil {
+		b := append([]byte(nil), bwt...)
+		translate(b, code)
+		t := InverseBWT(b, l)
+		translate(t, decode(code))
+		return t
+	}
+	return InverseBWT(bwt, l)
+}
+
+// bwt sorts with suffix array of int if wide, otherwise int32, see BWT
+func bwt(t []byte, wide bool, workers int) (int, []byte, *Aux) {
+	if len(t) == 0 {
+		// row 0 is sentinel and end of text
+		t = append(t, 0)
+		return 0, t, newAux(t, rankBWT(t), dictBWT(t))
+	}
+
+	var l int
+	var arr []uint
+	var dict []byte
+	if wide {
+		sa := make([]int, len(t))
+		// dict -> 0 -> 0, 1 -> 1, 2 -> '\n'
+		l, arr, dict = sais(bytebuf(t), sa, alphabetSize, true, false, workers)
+		t = append(t, 1)
+		for bi := 1; bi < len(t); bi++ {
+			t[bi] = byte(sa[bi-1])
+		}
+	} else {
+		sa := make([]int32, len(t))
+		l, arr, dict = sais32(bytebuf(t), sa, alphabetSize, true, false, workers)
+		t = append(t, 1)
+		for bi := 1; bi < len(t); bi++ {
+			t[bi] = byte(sa[bi-1])
+		}
+	}
+
+	return l + 1, t, newAux(t, arr, dict)
+}
+
+// bwtBinary transforms t of any byte into BWT as bwt, row 0 is sentinel, bwt[l] is (0) of the end of text,
+// returns l and BWT
+func bwtBinary(t []byte, wide bool, workers int) (int, []byte) {
+	n, u := len(t), symbols(t, false)
+	l := 0
+	t = append(t, 0)
+	if wide {
+		sa := make([]int, n)
+		if n > 1 {
+			sais(u, sa, alphabetSize+2, false, false, workers)
+		}
+		for i, p := range sa {
+			if p+1 < n {
+				t[i+1] = byte(u.get(p+1) - 2)
+			} else {
+				l, t[i+1] = i+1, 0
+			}
+		}
+	} else {
+		sa := make([]int32, n)
+		if n > 1 {
+			sais32(u, sa, alphabetSize+2, false, false, workers)
+		}
+		for i, p := range sa {
+			if int(p)+1 < n {
+				t[i+1] = byte(u.get(int(p)+1) - 2)
+			} else {
+				l, t[i+1] = i+1, 0
+			}
+		}
+	}
+
+	return l, t
+}
+
+// bwtStandard transforms t into BWT of t$ as libdivsufsort, returns the primary index, ie, the row of t
+// where row 0 is $, and BWT without $ in t
+func bwtStandard(t []byte, wide bool, workers int) (int, []byte) {
+	n := len(t)
+	if n == 0 {
+		return 0, t
+	}
+
+	// forward suffix of t starting at p is position n - 1 - p of t reversed read backwards,
+	// row 0 is $ followed by BWT of rows except the primary index, t[p-1] of row p is u[n-p]
+	u, l := symbols(t, true), 0
+	t[0] = byte(u.get(0) - 2)
+	if wide {
+		sa := make([]int, n)
+		if n > 1 {
+			sais(u, sa, alphabetSize+2, false, false, workers)
+		}
+		for i, j := 0, 1; i < n; i++ {
+			if q := sa[i]; q+1 < n {
+				t[j] = byte(u.get(q+1) - 2)
+				j++
+			} else {
+				l = i + 1
+			}
+		}
+	} else {
+		sa := make([]int32, n)
+		if n > 1 {
+			sais32(u, sa, alphabetSize+2, false, false, workers)
+		}
+		for i, j := 0, 1; i < n; i++ {
+			if q := int(sa[i]); q+1 < n {
+				t[j] = byte(u.get(q+1) - 2)
+				j++
+			} else {
+				l = i + 1
+			}
+		}
+	}
+
+	return l, t
+}
+
+// symbols returns bytes of t shifted by 2, away from sentinel and separator, reversed if rev
+func symbols(t []byte, rev bool) buf {
+	n := len(t)
+	if n > math.MaxInt32 {
+		u := make(intbuf, n)
+		for i, c := range t {
+			if rev {
+				i = n - 1 - i
+			}
+			u[i] = int(c) + 2
+		}
+		return u
+	}
+
+	u := make(intbuf32, n)
+	for i, c := range t {
+		if rev {
+			i = n - 1 - i
+		}
+		u[i] = int32(c) + 2
+	}
+	return u
+}
 
+// newAux creates Aux of bwt, arr is the rank counters of bwt, dict is ascending bytes of bwt
+func newAux(bwt []byte, arr []uint, dict []byte) *Aux {
 	// note: dict content is ascending, make sure byte 0 and byte 1 are indexed 0 and 1
 	if dict[0] != 0 {
 		dict = append([]byte{0, 1}, dict...)
-	} else if dict[1] != 1 {
+	} else if len(dict) < 2 || dict[1] != 1 {
 		dict = append([]byte{0, 1}, dict[1:]...)
 	}
 
 	// note: Dist starts with one ZERO value
-	aux := &Aux{uint(len(t)), make([][]uint, 256, 256), []uint{0}, []uint{}, dict}
+	aux := &Aux{uint(len(bwt)), make([][]uint, 256, 256), []uint{0}, []uint{}, dict, false, 0, 0, 0}
 	for i := range aux.Eob {
 		aux.Eob[i], arr = arr[:256], arr[256:]
 	}
@@ -119,20 +459,15 @@ func BWT(t []byte) (int, []byte, *Aux) {
 				reset(chars)
 				sum += r
 				for bi := sum - r; bi < sum; bi++ {
-					if bi == 0 {
-						chars[t[0]]++
+					if bwt[bi] == 0 {
+						chars[1]++
 					} else {
-						t[bi] = byte(sa[bi-1])
-						if t[bi] == 0 {
-							chars[1]++
-						} else {
-							chars[t[bi]]++
-						}
+						chars[bwt[bi]]++
 					}
 				}
 				for k, cnt := range chars {
 					if cnt > 0 {
-						aux.Hist = append(aux.Hist, (cnt<<8)|uint(k))
+						aux.Hist = packHist(aux.Hist, cnt, uint(k), maxHistCount)
 					}
 				}
 
@@ -142,11 +477,309 @@ func BWT(t []byte) (int, []byte, *Aux) {
 		}
 	}
 
-	return l + 1, t, aux
+	return aux
+}
+
+// newAuxBinary creates Aux of bwt returned by bwtBinary, l is the row of the end of text,
+// counters are of the symbols of other rows
+func newAuxBinary(bwt []byte, l int) *Aux {
+	arr := make([]uint, 256*256, 256*256)
+	rnk := make2Darr(arr, 256)
+	hist, _ := histgram(bytebuf(bwt), alphabetSize)
+	hist[0]--
+
+	// row 0 is sentinel, followed by buckets of symbols, the byte of a symbol is its bucket
+	dict, i := []byte{0, 1}, 1
+	if l != 0 {
+		rnk[bwt[0]][0]++
+	}
+	for c := 0; c < alphabetSize; c++ {
+		if hist[c] > 0 {
+			dict = append(dict, byte(c))
+		}
+		for e := i + hist[c]; i < e; i++ {
+			if i != l {
+				rnk[bwt[i]][c]++
+			}
+		}
+	}
+
+	aux := newAux(bwt, arr, dict)
+	aux.Binary, aux.End = true, uint(l)
+	return aux
+}
+
+// maximum count of a Hist entry, count is packed above the byte
+const maxHistCount = ^uint(0) >> 8
+
+// packHist appends (cnt<<8)|k to hist, counts larger than max, eg, 2^24 on 32 bit platforms,
+// are split into entries of the same byte, which sum up to cnt
+func packHist(hist []uint, cnt, k, max uint) []uint {
+	for ; cnt > max; cnt -= max {
+		hist = append(hist, (max<<8)|k)
+	}
+	return append(hist, (cnt<<8)|k)
+}
+
+// InverseBWT restores the text from bwt, l is the row of the end of text returned by BWT,
+// returns nil if bwt is empty, panics if l is not a row of bwt, or if LF mapping does not reach row l
+// within len(bwt) - 1 bytes, eg, bwt of another mode of Options
+func InverseBWT(bwt []byte, l int) []byte {
+	if len(bwt) == 0 {
+		return nil
+	}
+	checkRow(l, len(bwt))
+
+	hist, bkt := histgram(bytebuf(bwt), alphabetSize)
+	setBktBeg(bkt, hist)
+
+	// LF mapping, lf[i] is the row of the position next to row i,
+	// ie, bucket of bwt[i] plus occurrences of bwt[i] before row i
+	lf := make([]int, len(bwt))
+	for i, c := range bwt {
+		lf[i] = bkt[c]
+		bkt[c]++
+	}
+
+	// separators are ordered by their positions, not by occurrences, the k-th separator
+	// of the text is the k-th row of the separator bucket, which starts after the end of text
+	sep := hist[0]
+
+	// row 0 is sentinel, starts from the beginning of the text
+	t := make([]byte, 0, len(bwt)-1)
+	for i := 0; i != l; {
+		checkWalk(len(t), i, len(bwt))
+		c := bwt[i]
+		t = append(t, c)
+		if c == separator {
+			i = sep
+			sep++
+		} else {
+			i = lf[i]
+		}
+	}
+
+	return t
+}
+
+// inverseBinary restores the text from bwt returned by bwtBinary, bwt[l] is not a byte of the text
+func inverseBinary(bwt []byte, l int) []byte {
+	if len(bwt) == 0 {
+		return nil
+	}
+	checkRow(l, len(bwt))
+
+	hist, bkt := make([]int, alphabetSize), make([]int, alphabetSize)
+	for i, c := range bwt {
+		if i != l {
+			hist[c]++
+		}
+	}
+	setBktBeg(bkt, hist)
+
+	// LF mapping, row l maps to row 0 of sentinel, buckets start after it
+	lf := make([]int, len(bwt))
+	for i, c := range bwt {
+		if i != l {
+			lf[i] = bkt[c] + 1
+			bkt[c]++
+		}
+	}
+
+	t := make([]byte, 0, len(bwt)-1)
+	for i := 0; i != l; i = lf[i] {
+		checkWalk(len(t), i, len(bwt))
+		t = append(t, bwt[i])
+	}
+
+	return t
+}
+
+// inverseStandard restores the text from bwt and primary index l returned by bwtStandard
+func inverseStandard(bwt []byte, l int) []byte {
+	if len(bwt) == 0 {
+		return nil
+	}
+	// the primary index counts the row of $, which is not in bwt
+	checkRow(l, len(bwt)+1)
+
+	hist, bkt := histgram(bytebuf(bwt), alphabetSize)
+	setBktBeg(bkt, hist)
+
+	// LF mapping of bwt[j], rows start after row 0 of $
+	lf := make([]int, len(bwt))
+	for j, c := range bwt {
+		lf[j] = bkt[c] + 1
+		bkt[c]++
+	}
+
+	// from row 0 of $ backwards to the primary index, bwt skips the row of primary index
+	t := make([]byte, len(bwt))
+	for i, r := len(bwt)-1, 0; i >= 0; i-- {
+		j := r
+		if r > l {
+			j--
+		}
+		t[i], r = bwt[j], lf[j]
+	}
+
+	return t
+}
+
+// checkRow panics if l is not a row of n rows
+func checkRow(l, n int) {
+	if l < 0 || l >= n {
+		panic("sa: row of the end of text is out of range")
+	}
+}
+
+// checkWalk panics if LF mapping at row i of n rows has restored n - 1 bytes of text without reaching
+// the end of text, or i is not a row, ie, bwt is not of the mode of its inverse or corrupted
+func checkWalk(steps, i, n int) {
+	if steps >= n-1 || i >= n {
+		panic("sa: LF mapping does not reach the row of the end of text")
+	}
+}
+
+// RotationBWT transforms t into BWT of its cyclic rotations as bzip2 does, t can contain any byte,
+// there is no sentinel or separator. Returns the row of t among the sorted rotations and BWT,
+// bwt[i] is the last byte of the i-th smallest rotation.
+func RotationBWT(t []byte) (int, []byte) {
+	n := len(t)
+	bwt := make([]byte, n)
+	if n < 2 {
+		copy(bwt, t)
+		return 0, bwt
+	}
+
+	// forward suffixes of tt starting in [0, n) are in the order of rotations,
+	// sais orders positions by the text read backwards, so sort tt reversed.
+	// bytes are shifted by 2, away from sentinel and separator
+	u, sa := make(intbuf, 2*n), make([]int, 2*n)
+	for i, c := range t {
+		u[n-1-i], u[2*n-1-i] = int(c)+2, int(c)+2
+	}
+	sais(u, sa, alphabetSize+2, false, false, 1)
+
+	l, i := 0, 0
+	for _, p := range sa {
+		// p in reversed tt is the suffix of tt starting at 2n - 1 - p
+		if q := 2*n - 1 - p; q < n {
+			if q == 0 {
+				l = i
+				q = n
+			}
+			bwt[i] = t[q-1]
+			i++
+		}
+	}
+
+	return l, bwt
+}
+
+// SuffixArray returns the suffix array of t, see SuffixArrayTo
+func SuffixArray(t []byte) []int {
+	return (*Options)(nil).SuffixArray(t)
+}
+
+// SuffixArray returns the suffix array of t with options, see SuffixArrayTo
+func (o *Options) SuffixArray(t []byte) []int {
+	sa := make([]int, len(t))
+	o.SuffixArrayTo(t, sa)
+	return sa
+}
+
+// SuffixArrayTo writes the suffix array of t into sa[:len(t)], sa must be at least as long as t.
+//
+// Sentinel starts from the beginning of the text, positions are ordered by the text read backwards
+// from each position, ie, sa[i] < sa[j] if t[sa[i]], t[sa[i]-1], ... is less than t[sa[j]], t[sa[j]-1], ...
+// Byte value (1) divides multi strings, separators sort before any other byte and by their positions,
+// so common prefixes never span two strings. Strings can be empty, ie, t can start or end with
+// separator, or have consecutive separators. t must not contain byte value (0).
+func SuffixArrayTo(t []byte, sa []int) {
+	(*Options)(nil).SuffixArrayTo(t, sa)
+}
+
+// SuffixArrayTo writes the suffix array of t into sa with options, see SuffixArrayTo
+func (o *Options) SuffixArrayTo(t []byte, sa []int) {
+	if code := o.alphabet(); code != nil {
+		t = append([]byte(nil), t...)
+		translate(t, code)
+	}
+
+	sa = sa[:len(t)]
+	for i := range sa {
+		sa[i] = 0
+	}
+
+	switch {
+	case len(t) == 0:
+	case len(t) == 1:
+		// nothing to sort, and sais requires at least 2 bytes
+		sa[0] = 0
+	case o.standard():
+		// forward suffix of t starting at p is position n - 1 - p of t reversed read backwards
+		sais(symbols(t, true), sa, alphabetSize+2, false, false, o.workers())
+		for i, q := range sa {
+			sa[i] = len(t) - 1 - q
+		}
+	case o.binary():
+		sais(symbols(t, false), sa, alphabetSize+2, false, false, o.workers())
+	default:
+		sais(bytebuf(t), sa, alphabetSize, false, false, o.workers())
+	}
+}
+
+// SuffixArray32 returns the suffix array of t as int32, see SuffixArrayTo32
+func SuffixArray32(t []byte) []int32 {
+	return (*Options)(nil).SuffixArray32(t)
+}
+
+// SuffixArray32 returns the suffix array of t as int32 with options, see SuffixArrayTo32
+func (o *Options) SuffixArray32(t []byte) []int32 {
+	sa := make([]int32, len(t))
+	o.SuffixArrayTo32(t, sa)
+	return sa
+}
+
+// SuffixArrayTo32 writes the suffix array of t into sa[:len(t)] as SuffixArrayTo does, in half of the memory.
+// t must be shorter than 2^31.
+func SuffixArrayTo32(t []byte, sa []int32) {
+	(*Options)(nil).SuffixArrayTo32(t, sa)
+}
+
+// SuffixArrayTo32 writes the suffix array of t into sa as int32 with options, see SuffixArrayTo32
+func (o *Options) SuffixArrayTo32(t []byte, sa []int32) {
+	if len(t) > math.MaxInt32 {
+		panic("sa: text is too long for int32 suffix array")
+	}
+	if code := o.alphabet(); code != nil {
+		t = append([]byte(nil), t...)
+		translate(t, code)
+	}
+
+	sa = sa[:len(t)]
+	for i := range sa {
+		sa[i] = 0
+	}
+
+	// note: sorting is shared with SuffixArrayTo, sais32 is generated from sais
+	switch {
+	case len(t) < 2:
+	case o.standard():
+		sais32(symbols(t, true), sa, alphabetSize+2, false, false, o.workers())
+		for i, q := range sa {
+			sa[i] = int32(len(t)-1) - q
+		}
+	case o.binary():
+		sais32(symbols(t, false), sa, alphabetSize+2, false, false, o.workers())
+	default:
+		sais32(bytebuf(t), sa, alphabetSize, false, false, o.workers())
+	}
 }
 
-// text, sa, alphabet size, output as bwt, recursive
-func sais(t buf, sa []int, k int, bwt, rec bool) (int, []uint, []byte) {
+// text, sa, alphabet size, output as bwt, recursive, number of goroutines
+func sais(t buf, sa []int, k int, bwt, rec bool, workers int) (int, []uint, []byte) {
 	// scan text to create distribution histgram
 	hist, bkt := histgram(t, k)
 
@@ -157,13 +790,13 @@ func sais(t buf, sa []int, k int, bwt, rec bool) (int, []uint, []byte) {
 	// └─*─*─*─#─*─*-*─#──*──┘
 	// m = 9, ms = 2
 	m, ms := findLMS(t, sa, bkt, hist, rec)
-	if m > 1 || ms > 1 {
+	if m > 1 {
 		// inducing sort LMS substrings into their relative positions, including separators, except sentinel
 		// ┌0─┬───┬──┬──┬───┬5─┬───┬───┬───┬──┬10┬──┬──┬──┬──┬15┬──┬──┬──┬──┬20┐
 		// │-8│-16│  │  │-19│-2│-10│-14│-12│-6│-4│  │  │  │  │  │  │  │  │  │  │
 		// └▲─┴───┼──┴──┴───┼──┴───┴───┴───┴──┴▲─┼──┴──┼──┴──┼──┴──┴──┴──┴──┴──┤
 		//       sep        a                    i     m     n                 s
-		sortLMS(t, sa, bkt, hist, ms)
+		sortLMS(t, sa, bkt, hist, ms, workers)
 
 		// name LMS substrings in lexicographic order, n -> number of LMS with unique name
 		//        ┌────────────────────────────────────────────────┐
@@ -171,7 +804,7 @@ func sais(t buf, sa []int, k int, bwt, rec bool) (int, []uint, []byte) {
 		// │ 7│15│18│ 1│ 9│13│11│ 5│ 3│ 4│ 6│ 6│ 1│ 5│ 6│ 6│ 2│  │ 3│  │  │
 		// └──┴──┴──┴──┴──┴──┴──┴──┴──▲─-┴──┴──┴──┴──┴──┴──┴──┴──┴──┴──┴──┘
 		//                            │m
-		n := nameLMS(t, sa, m, rec)
+		n := nameLMS(t, sa, m, rec, workers)
 
 		if n < m {
 			// there are more than one LMS strings with the same lexicographical order
@@ -188,7 +821,7 @@ func sais(t buf, sa []int, k int, bwt, rec bool) (int, []uint, []byte) {
 			// │ 3│ 7│ 8│ 0│ 4│ 1│ 5│ 2│ 6│ 3│ 5│ 5│ 0│ 4│ 5│ 5│ 1│ 2│  │  │  │
 			// └──┴──┴──┴──┴──┴──┴──┴──┴──▲──┴──┴──┴──┴──┴──┴──┴──┴──┴──┴──┴──┘
 			//                            │m
-			sais(intbuf(sa[m:2*m]), sa[:m], n+1, false, true)
+			sais(intbuf(sa[m:2*m]), sa[:m], n+1, false, true, workers)
 
 			// locate and shuffle LMS into lexicographic order in sa[:m]
 			// ┌0─┬──┬──┬──┬──┬5─┬──┬──┬──┬──┬10┬──┬──┬──┬──┬15┬──┬──┬──┬──┬20┐
@@ -207,16 +840,20 @@ func sais(t buf, sa []int, k int, bwt, rec bool) (int, []uint, []byte) {
 		// │8 │16│  │  │19│ 2│10│ 4│12│ 6│14│  │  │  │  │  │  │  │  │  │  │
 		// └──┴──┼──┴──┴──┼──┴──┴──┴──┴──┴──┼──┴──┼──┴──┼──┴──┴──┴──┴──┴──┤
 		//      sep       a                 i     m     n                 s
-		restoreLMS(t, sa, bkt, hist, m)
+		restoreLMS(t, sa, bkt, hist, m, ms)
 	}
 
 	if bwt {
-		return induceBWT(t, sa, bkt, hist, ms)
+		return induceBWT(t, sa, bkt, hist, ms, workers)
 	}
-	return induce(t, sa, bkt, hist, ms), nil, nil
+	return induce(t, sa, bkt, hist, ms, workers), nil, nil
 }
 
-func induce(t buf, sa, bkt, hist []int, ms int) int {
+func induce(t buf, sa, bkt, hist []int, ms, workers int) int {
+	if workers > 1 && t.len() >= parMinLen {
+		return induceBlock(t, sa, bkt, hist, ms, workers)
+	}
+
 	setBktBeg(bkt, hist)
 
 	// sentinel is LMS, T[0] is L type
@@ -226,14 +863,17 @@ func induce(t buf, sa, bkt, hist []int, ms int) int {
 	//      sep       a                 i     m     n                 s
 	n, p, end := 0, t.get(0), t.len()
 	b := bkt[p]
-	if p > t.get(1) {
-		// next suffix is S type, put ^sa[i]
-		sa[b] = ^0
-	} else {
-		// next suffix is L type, put sa[i] + 1
-		sa[b] = 1
+	if ms == 0 || p != separator {
+		// note: if t starts with separator, it is in place, see findLMS
+		if p > t.get(1) {
+			// next suffix is S type, put ^sa[i]
+			sa[b] = ^0
+		} else {
+			// next suffix is L type, put sa[i] + 1
+			sa[b] = 1
+		}
+		b++
 	}
-	b++
 
 	// scan sa from left to right to induce L type
 	// ┌0─┬───┬───┬──┬───┬5─┬───┬──┬───┬──┬─10┬──┬──┬──┬──┬15┬──┬──┬──┬──┬20┐
@@ -299,6 +939,10 @@ func induce(t buf, sa, bkt, hist []int, ms int) int {
 			bkt[p], b, p = b, bkt[n], n
 		}
 		b--
+		if b < ms {
+			// separators are at the start of SA, they are sorted
+			continue
+		}
 		if s == end-1 || p < t.get(s+1) {
 			// next suffix is L type
 			sa[b] = ^s
@@ -310,6 +954,79 @@ func induce(t buf, sa, bkt, hist []int, ms int) int {
 	return -1
 }
 
+// induceBlock is induce in blocks of SA, workers read the text and write induced suffixes of a block
+// concurrently, see blockScan
+func induceBlock(t buf, sa, bkt, hist []int, ms, workers int) int {
+	setBktBeg(bkt, hist)
+	p, end := t.get(0), t.len()
+	b := bkt[p]
+	if ms == 0 || p != separator {
+		if p > t.get(1) {
+			sa[b] = ^0
+		} else {
+			sa[b] = 1
+		}
+		b++
+	}
+
+	c := newBlockScan(t, workers, func(i int) int { return sa[i] }, func(i, v int) { sa[i] = v })
+
+	// induce L type, same as induce
+	c.scan(len(sa), 0, true, func(i int) {
+		s := c.get(i)
+		if s == 0 {
+			return
+		} else if s < 0 {
+			c.set(i, ^s)
+			return
+		}
+		if s >= end {
+			c.set(i, ^(s - 1))
+			return
+		}
+
+		n, x := c.sym(i, s)
+		c.set(i, ^(s - 1))
+		if p != n {
+			bkt[p], b, p = b, bkt[n], n
+		}
+		if s+1 < end && p > x {
+			c.set(b, ^s)
+		} else {
+			c.set(b, s+1)
+		}
+		b++
+	})
+
+	// induce S type, same as induce
+	setBktEnd(bkt, hist)
+	p, b = 0, bkt[0]
+	c.scan(len(sa), 1, false, func(i int) {
+		s := c.get(i)
+		if s < 0 {
+			c.set(i, ^s)
+			return
+		}
+		if s++; s == end {
+			return
+		}
+
+		n, x := c.sym(i, s)
+		if p != n {
+			bkt[p], b, p = b, bkt[n], n
+		}
+		if b--; b < ms {
+			return
+		}
+		if s == end-1 || p < x {
+			c.set(b, ^s)
+		} else {
+			c.set(b, s)
+		}
+	})
+	return -1
+}
+
 func updateRank(rank [][]uint, a, b int) {
 	if a < 1 {
 		a = 1
@@ -319,7 +1036,11 @@ func updateRank(rank [][]uint, a, b int) {
 }
 
 // same as induce except, it produces BWT and data structure for merging BWT
-func induceBWT(t buf, sa, bkt, hist []int, ms int) (int, []uint, []byte) {
+func induceBWT(t buf, sa, bkt, hist []int, ms, workers int) (int, []uint, []byte) {
+	if workers > 1 && t.len() >= parMinLen {
+		return induceBWTBlock(t, sa, bkt, hist, ms, workers)
+	}
+
 	cnt := countBktBeg(bkt, hist)
 	ptr, dict, blk, rnk := makeCounters(bkt, hist, cnt)
 
@@ -330,14 +1051,17 @@ func induceBWT(t buf, sa, bkt, hist []int, ms int) (int, []uint, []byte) {
 	//      sep       a                 i     m     n                 s
 	n, p, end := 0, t.get(0), t.len()
 	b := bkt[p]
-	if t.len() > 1 && p > t.get(1) {
-		// next suffix is S type, put ^sa[i]
-		sa[b] = ^0
-	} else {
-		// next suffix is L type, put sa[i] + 1
-		sa[b] = 1
+	if ms == 0 || p != separator {
+		// note: if t starts with separator, it is in place, see findLMS
+		if t.len() > 1 && p > t.get(1) {
+			// next suffix is S type, put ^sa[i]
+			sa[b] = ^0
+		} else {
+			// next suffix is L type, put sa[i] + 1
+			sa[b] = 1
+		}
+		b++
 	}
-	b++
 	// rnk[p][1]++
 	updateRank(rnk, p, 1)
 
@@ -419,14 +1143,17 @@ func induceBWT(t buf, sa, bkt, hist []int, ms int) (int, []uint, []byte) {
 			bkt[p], b, p = b, bkt[n], n
 		}
 		b--
+		if b < ms {
+			// separators are at the start of SA, they are sorted
+			continue
+		}
 		if s == end-1 || p < t.get(s+1) {
 			// next suffix is L type
 			if s == end-1 {
-				// must not have separator at the end of buffer
 				l = b
 				sa[b] = ^0
-			} else if b >= ms {
-				// only if sa[b] is not separator, bwt is T[sa[i] + 1]
+			} else {
+				// bwt is T[sa[i] + 1]
 				sa[b] = ^t.get(s + 1)
 			}
 		} else {
@@ -437,6 +1164,95 @@ func induceBWT(t buf, sa, bkt, hist []int, ms int) (int, []uint, []byte) {
 	return l, blk, dict
 }
 
+// induceBWTBlock is induceBWT in blocks of SA, see induceBlock
+func induceBWTBlock(t buf, sa, bkt, hist []int, ms, workers int) (int, []uint, []byte) {
+	cnt := countBktBeg(bkt, hist)
+	ptr, dict, blk, rnk := makeCounters(bkt, hist, cnt)
+	p, end := t.get(0), t.len()
+	b := bkt[p]
+	if ms == 0 || p != separator {
+		if t.len() > 1 && p > t.get(1) {
+			sa[b] = ^0
+		} else {
+			sa[b] = 1
+		}
+		b++
+	}
+	updateRank(rnk, p, 1)
+
+	c := newBlockScan(t, workers, func(i int) int { return sa[i] }, func(i, v int) { sa[i] = v })
+
+	// induce L type, same as induceBWT
+	c.scan(len(sa), 0, true, func(i int) {
+		s := c.get(i)
+		if s == 0 {
+			return
+		} else if s < 0 {
+			c.set(i, ^s)
+			return
+		}
+		if s >= end {
+			c.set(i, end-1)
+			return
+		}
+
+		n, x := c.sym(i, s)
+		c.set(i, ^n)
+		if p != n {
+			bkt[p], b, p = b, bkt[n], n
+		}
+		if s+1 < end && p > x {
+			c.set(b, ^s)
+		} else {
+			c.set(b, s+1)
+		}
+		b++
+	})
+
+	// induce S type, same as induceBWT
+	setBktEnd(bkt, hist)
+	p, b = 0, bkt[0]
+	l, idx := 0, cnt-1
+	c.scan(len(sa), 1, false, func(i int) {
+		if i < ptr[idx] {
+			idx--
+		}
+		s := c.get(i)
+		if s < 0 {
+			c.set(i, ^s)
+			updateRank(rnk, ^s, int(dict[idx]))
+			return
+		}
+		if s++; s == end {
+			l = i
+			c.set(i, 0)
+			updateRank(rnk, 1, int(dict[idx]))
+			return
+		}
+
+		n, x := c.sym(i, s)
+		c.set(i, n)
+		updateRank(rnk, n, int(dict[idx]))
+		if p != n {
+			bkt[p], b, p = b, bkt[n], n
+		}
+		if b--; b < ms {
+			return
+		}
+		if s == end-1 || p < x {
+			if s == end-1 {
+				l = b
+				c.set(b, ^0)
+			} else {
+				c.set(b, ^x)
+			}
+		} else {
+			c.set(b, s)
+		}
+	})
+	return l, blk, dict
+}
+
 // place named LMS substrings to sa[m:2m]
 func adjustLMS(sa []int, m int) {
 	// reset sa[:m]
@@ -467,7 +1283,8 @@ func adjustLMS(sa []int, m int) {
 // └──┴──┴──┴──┴──┴──┴──┴──┴──▲──┴──┴──┴──┴──┴──┴──┴──┴──┴──┴──┴──┘
 //                            │m
 func locateLMS(t buf, sa []int, m int, rec bool) {
-	p, s := t.get(0), false
+	p := t.get(0)
+	s := !rec && p == separator
 	for i, e := 1, t.len(); i < e; i++ {
 		c := t.get(i)
 		if s {
@@ -503,11 +1320,14 @@ func clearLMSLen(sa []int, m int) {
 // │8 │16│  │  │19│ 2│10│ 4│12│ 6│14│  │  │  │  │  │  │  │  │  │  │
 // └──┴──┼──┴──┴──┼──┴──┴──┴──┴──┴──┼──┴──┼──┴──┼──┴──┴──┴──┴──┴──┤
 //      sep       a                 i     m     n                 s
-func restoreLMS(t buf, sa, bkt, hist []int, m int) {
+func restoreLMS(t buf, sa, bkt, hist []int, m, ms int) {
 	setBktEnd(bkt, hist)
-	p, b := 0, bkt[0]
-	for i := m - 1; i >= 0; i-- {
+	p, b, i := 0, bkt[0], m-1
+	for ; i >= 0; i-- {
 		c := t.get(sa[i])
+		if ms > 0 && c == separator {
+			break
+		}
 		if p != c {
 			bkt[p], b, p = b, bkt[c], c
 		}
@@ -518,6 +1338,25 @@ func restoreLMS(t buf, sa, bkt, hist []int, m int) {
 			sa[i]++
 		}
 	}
+	if ms == 0 {
+		return
+	}
+
+	// sa[:i+1] are LMS separators by position, put all separators in place as findLMS,
+	// from the end, separators of empty strings precede a LMS separator, or end the text
+	j, q := ms-1, t.len()-1
+	for ; t.get(q) == separator; q-- {
+		sa[j] = ^q
+		j--
+	}
+	for ; i >= 0; i-- {
+		q = sa[i]
+		sa[j] = q + 1
+		for j--; q > 0 && t.get(q-1) == separator; j-- {
+			q--
+			sa[j] = ^q
+		}
+	}
 }
 
 // return number of LMS excluding sentinel
@@ -526,6 +1365,7 @@ func restoreLMS(t buf, sa, bkt, hist []int, m int) {
 // └─*─*─*─#─*─*-*─#──*──┘
 // Separators have builtin ascending lexicographic order. ie $[7] < $[15] in above example
 // No need to sort separators. Place separators from start of the bucket to sort LMS as sentinel.
+// Separators of empty strings, ie, leading, trailing or consecutive, are placed as ^p, they induce nothing.
 // Induce sort LMS into its relative positions. Note: SA contains the offset of L suffix for LMS.
 // ┌0─┬──┬──┬──┬──┬5─┬──┬──┬──┬──┬10┬──┬──┬──┬──┬15┬──┬──┬──┬──┬20┐
 // │8 │16│  │  │  │  │12│10│  │4 │2 │  │  │  │  │  │  │  │  │  │  │
@@ -535,12 +1375,12 @@ func findLMS(t buf, sa, bkt, hist []int, rec bool) (int, int) {
 	setBktEnd(bkt, hist)
 	m, ms, l, lms, sbkt := 0, 0, 0, -1, bkt[separator-1]
 	p := t.get(0)
-	s := false
-	for i, e := 1, t.len(); i < e; i++ {
+	// separator at the start is S type as the one after a string, the sentinel is not LMS
+	s := !rec && p == separator
+	e := t.len()
+	for i := 1; i < e; i++ {
 		// p -> t[i - 1], c -> t[i]
 		c := t.get(i)
-		// TODO: check p == c == separator, separator is LMS,
-		// for two separators, one is S, the next one is LMS
 		if s && p < c {
 			// p -> LMS, m -> number of LMS
 			m++
@@ -562,9 +1402,20 @@ func findLMS(t buf, sa, bkt, hist []int, rec bool) (int, int) {
 		} else if p > c {
 			// if s true, p == c, then, c is S as well
 			s = true
+		} else if !rec && p == separator {
+			// empty string, p == c == separator, p is S type but not LMS,
+			// put ^p in place, it induces nothing
+			sa[sbkt] = ^(i - 1)
+			sbkt++
+			ms++
 		}
 		p = c
 	}
+	if !rec && p == separator {
+		// t ends with separator, empty string at the end
+		sa[sbkt] = ^(e - 1)
+		ms++
+	}
 	if m == 1 && lms >= 0 {
 		// note: l is the L suffix to the right of LMS
 		// add L of the sentinel, will go induce directly
@@ -574,7 +1425,7 @@ func findLMS(t buf, sa, bkt, hist []int, rec bool) (int, int) {
 	return m, ms
 }
 
-func nameLMS(t buf, sa []int, m int, rec bool) int {
+func nameLMS(t buf, sa []int, m int, rec bool, workers int) int {
 	// compact all the sorted substrings into the first m items of SA
 	// 2*m must be not larger than n (proveable)
 	// ┌0─┬──┬──┬──┬──┬5─┬──┬──┬──┬──┬10┬──┬──┬──┬──┬15┬──┬──┬──┬──┬20┐
@@ -601,7 +1452,8 @@ func nameLMS(t buf, sa []int, m int, rec bool) int {
 	// └──┴──┴──┴──┴──┴──┴──┴──┴──▲──┴──┴──┴──┴──┴──┴──┴──┴──┴──┴──┴──┘
 	//                            │m
 	// note: sa[3] is 18, it's length is at sa[m + 18 >> 1], ie sa[9 + 9]
-	p, j, s := t.get(0), 0, false
+	p, j := t.get(0), 0
+	s := !rec && p == separator
 	for i, e := 1, t.len(); i < e; i++ {
 		n := t.get(i)
 		if s && p < n {
@@ -626,40 +1478,61 @@ func nameLMS(t buf, sa []int, m int, rec bool) int {
 	// │ 7│15│18│ 1│ 9│13│11│ 5│ 3│ 4│ 6│ 6│ 1│ 5│ 6│ 6│ 2│  │ 3│  │  │
 	// └──┴──┴──┴──┴──┴──┴──┴──┴──▲─-┴──┴──┴──┴──┴──┴──┴──┴──┴──┴──┴──┘
 	//                            │m
+	// compare adjacent LMS substrings in parallel before naming, diff[i] is true if sa[i-1] and sa[i] differ
+	var diff []bool
+	if workers > 1 && m >= parMinLen {
+		diff = make([]bool, m)
+		parallel(workers, m-1, func(lo, hi int) {
+			for i := lo + 1; i <= hi; i++ {
+				x, y := sa[i-1], sa[i]
+				diff[i] = diffLMS(t, x, y, sa[m+x>>1], sa[m+y>>1], rec)
+			}
+		})
+	}
+
 	plen, b, n := -1, sa[0]>>1, 1
 	plen, sa[m+b] = sa[m+b], n
 	for i := 1; i < m; i++ {
 		b = sa[i] >> 1
-		diff := true
-		if plen > 0 && plen == sa[m+b] {
-			// two LMS suffix with same length and not separators
-			x, y, l := sa[i-1], sa[i], 0
-			for l < plen && x >= 0 && y >= 0 && t.eq(x, y) {
-				l++
-				x--
-				y--
-			}
-			// one char in two suffix is different if l < plen
-			diff = l < plen
-			if !rec && !diff && x >= 0 && y >= 0 && t.get(x) == separator && t.get(y) == separator {
-				// if two LMS substrings are equal but ends with separators, they should be different
-				diff = true
-			}
-		} else {
-			// two suffix with different length, must be different
-			plen = sa[m+b]
-		}
-		if diff {
+		if diff != nil && diff[i] || diff == nil && diffLMS(t, sa[i-1], sa[i], plen, sa[m+b], rec) {
 			n++
 		}
-		sa[m+b] = n
+		plen, sa[m+b] = sa[m+b], n
 	}
 
 	return n
 }
 
+// diffLMS returns true if LMS substrings ending at x and y of length xl and yl are different
+func diffLMS(t buf, x, y, xl, yl int, rec bool) bool {
+	if xl <= 0 || xl != yl {
+		// two suffix with different length or separators, must be different
+		return true
+	}
+
+	// two LMS suffix with same length and not separators
+	l := 0
+	for l < xl && x >= 0 && y >= 0 && t.eq(x, y) {
+		l++
+		x--
+		y--
+	}
+	// one char in two suffix is different if l < xl
+	if l < xl {
+		return true
+	}
+
+	// if two LMS substrings are equal but ends with separators, they should be different
+	return !rec && x >= 0 && y >= 0 && t.get(x) == separator && t.get(y) == separator
+}
+
 // sort LMS, note: LMS in sa[] contains L suffix
-func sortLMS(t buf, sa, bkt, hist []int, ms int) {
+func sortLMS(t buf, sa, bkt, hist []int, ms, workers int) {
+	if workers > 1 && t.len() >= parMinLen {
+		sortLMSBlock(t, sa, bkt, hist, ms, workers)
+		return
+	}
+
 	setBktBeg(bkt, hist)
 	// T[0] is L type suffix, set sa[0]
 	// moving forward store next char b in sa
@@ -668,14 +1541,17 @@ func sortLMS(t buf, sa, bkt, hist []int, ms int) {
 	// sort sentinel, T[0] is always L suffix because sentinel is LMS
 	p, sz := t.get(0), t.len()
 	b := bkt[p]
-	if p > t.get(1) {
-		// T[1] is S
-		sa[b] = ^1
-	} else {
-		// T[1] is L
-		sa[b] = 1
+	if ms == 0 || p != separator {
+		// note: if t starts with separator, it is in place, see findLMS
+		if p > t.get(1) {
+			// T[1] is S
+			sa[b] = ^1
+		} else {
+			// T[1] is L
+			sa[b] = 1
+		}
+		b++
 	}
-	b++
 
 	// sort L type
 	// ┌0─┬───┬──┬──┬──┬5─┬──┬──┬──┬──┬10┬──┬──┬──┬──┬15┬──┬──┬──┬──┬20┐
@@ -734,25 +1610,98 @@ func sortLMS(t buf, sa, bkt, hist []int, ms int) {
 			}
 			b--
 			s++
+			sa[i] = 0
+			if b < ms {
+				// separators are at the start of SA, they are sorted
+				continue
+			}
 			if s >= sz {
 				// reached end of buffer, there is no LMS suffix, stop
 				sa[b] = 0
 			} else if p < t.get(s) {
-				// next suffix is L type
-				if b >= ms {
-					// LMS suffix, no need if b < ms, separators are sorted
-					// note: sa[i], ie s, contains offset of L suffix, ie sa[j] + 1
-					sa[b] = ^(s - 1)
-				}
+				// next suffix is L type, LMS suffix
+				// note: sa[i], ie s, contains offset of L suffix, ie sa[j] + 1
+				sa[b] = ^(s - 1)
 			} else {
 				// next suffix is S type
 				sa[b] = s
 			}
-			sa[i] = 0
 		}
 	}
 }
 
+// sortLMSBlock is sortLMS in blocks of SA, see induceBlock
+func sortLMSBlock(t buf, sa, bkt, hist []int, ms, workers int) {
+	setBktBeg(bkt, hist)
+	p, sz := t.get(0), t.len()
+	b := bkt[p]
+	if ms == 0 || p != separator {
+		if p > t.get(1) {
+			sa[b] = ^1
+		} else {
+			sa[b] = 1
+		}
+		b++
+	}
+
+	c := newBlockScan(t, workers, func(i int) int { return sa[i] }, func(i, v int) { sa[i] = v })
+
+	// sort L type, same as sortLMS
+	c.scan(len(sa), 0, true, func(i int) {
+		s := c.get(i)
+		if s < 0 {
+			c.set(i, ^s)
+			return
+		} else if s == 0 {
+			return
+		}
+
+		n, x := c.sym(i, s)
+		if p != n {
+			bkt[p], b, p = b, bkt[n], n
+		}
+		if s++; s >= sz {
+			c.set(b, 0)
+		} else if p > x {
+			c.set(b, ^s)
+		} else {
+			c.set(b, s)
+		}
+		b++
+		if i >= ms {
+			c.set(i, 0)
+		} else {
+			c.set(i, ^(c.get(i) - 1))
+		}
+	})
+
+	// sort S type, same as sortLMS
+	setBktEnd(bkt, hist)
+	b, p = bkt[0], 0
+	c.scan(len(sa), 0, false, func(i int) {
+		s := c.get(i)
+		if s <= 0 {
+			return
+		}
+
+		n, x := c.sym(i, s)
+		if p != n {
+			bkt[p], b, p = b, bkt[n], n
+		}
+		c.set(i, 0)
+		if b--; b < ms {
+			return
+		}
+		if s++; s >= sz {
+			c.set(b, 0)
+		} else if p < x {
+			c.set(b, ^(s - 1))
+		} else {
+			c.set(b, s)
+		}
+	})
+}
+
 func histgram(t buf, k int) ([]int, []int) {
 	h := make([]int, k)
 
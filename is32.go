@@ -0,0 +1,643 @@
+// Code generated by gen32.go; DO NOT EDIT.
+
+package sa
+
+func sais32(t buf, sa []int32, k int, bwt, rec bool, workers int) (int, []uint, []byte) {
+	hist, bkt := histgram(t, k)
+	m, ms := findLMS32(t, sa, bkt, hist, rec)
+	if m > 1 {
+		sortLMS32(t, sa, bkt, hist, ms, workers)
+		n := nameLMS32(t, sa, m, rec, workers)
+		if n < m {
+			adjustLMS32(sa, m)
+			sais32(intbuf32(sa[m:2*m]), sa[:m], n+1, false, true, workers)
+			locateLMS32(t, sa, m, rec)
+			shuffleLMS32(sa, m)
+		} else {
+			clearLMSLen32(sa, m)
+		}
+		restoreLMS32(t, sa, bkt, hist, m, ms)
+	}
+	if bwt {
+		return induceBWT32(t, sa, bkt, hist, ms, workers)
+	}
+	return induce32(t, sa, bkt, hist, ms, workers), nil, nil
+}
+
+func induce32(t buf, sa []int32, bkt, hist []int, ms, workers int) int {
+	if workers > 1 && t.len() >= parMinLen {
+		return induceBlock32(t, sa, bkt, hist, ms, workers)
+	}
+	setBktBeg(bkt, hist)
+	n, p, end := 0, t.get(0), t.len()
+	b := bkt[p]
+	if ms == 0 || p != separator {
+		if p > t.get(1) {
+			sa[b] = int32(^0)
+		} else {
+			sa[b] = int32(1)
+		}
+		b++
+	}
+	for i, s32 := range sa {
+		s := int(s32)
+		if s == 0 {
+			continue
+		} else if s < 0 {
+			sa[i] = int32(^s)
+			continue
+		}
+		if s >= end {
+			sa[i] = int32(^(s - 1))
+			continue
+		}
+		sa[i] = int32(^(s - 1))
+		n = t.get(s)
+		if p != n {
+			bkt[p], b, p = b, bkt[n], n
+		}
+		if s+1 < end && p > t.get(s+1) {
+			sa[b] = int32(^s)
+		} else {
+			sa[b] = int32(s + 1)
+		}
+		b++
+	}
+	setBktEnd(bkt, hist)
+	p, b = 0, bkt[0]
+	for i := end - 1; i >= 0; i-- {
+		s := int(sa[i])
+		if s < 0 {
+			sa[i] = int32(^s)
+			continue
+		}
+		s++
+		if s == end {
+			continue
+		}
+		n = t.get(s)
+		if p != n {
+			bkt[p], b, p = b, bkt[n], n
+		}
+		b--
+		if b < ms {
+			continue
+		}
+		if s == end-1 || p < t.get(s+1) {
+			sa[b] = int32(^s)
+		} else {
+			sa[b] = int32(s)
+		}
+	}
+	return -1
+}
+
+func induceBlock32(t buf, sa []int32, bkt, hist []int, ms, workers int) int {
+	setBktBeg(bkt, hist)
+	p, end := t.get(0), t.len()
+	b := bkt[p]
+	if ms == 0 || p != separator {
+		if p > t.get(1) {
+			sa[b] = int32(^0)
+		} else {
+			sa[b] = int32(1)
+		}
+		b++
+	}
+	c := newBlockScan(t, workers, func(i int) int {
+		return int(sa[i])
+	}, func(i, v int) {
+		sa[i] = int32(v)
+	})
+	c.scan(len(sa), 0, true, func(i int) {
+		s := c.get(i)
+		if s == 0 {
+			return
+		} else if s < 0 {
+			c.set(i, ^s)
+			return
+		}
+		if s >= end {
+			c.set(i, ^(s - 1))
+			return
+		}
+		n, x := c.sym(i, s)
+		c.set(i, ^(s - 1))
+		if p != n {
+			bkt[p], b, p = b, bkt[n], n
+		}
+		if s+1 < end && p > x {
+			c.set(b, ^s)
+		} else {
+			c.set(b, s+1)
+		}
+		b++
+	})
+	setBktEnd(bkt, hist)
+	p, b = 0, bkt[0]
+	c.scan(len(sa), 1, false, func(i int) {
+		s := c.get(i)
+		if s < 0 {
+			c.set(i, ^s)
+			return
+		}
+		if s++; s == end {
+			return
+		}
+		n, x := c.sym(i, s)
+		if p != n {
+			bkt[p], b, p = b, bkt[n], n
+		}
+		if b--; b < ms {
+			return
+		}
+		if s == end-1 || p < x {
+			c.set(b, ^s)
+		} else {
+			c.set(b, s)
+		}
+	})
+	return -1
+}
+
+func induceBWT32(t buf, sa []int32, bkt, hist []int, ms, workers int) (int, []uint, []byte) {
+	if workers > 1 && t.len() >= parMinLen {
+		return induceBWTBlock32(t, sa, bkt, hist, ms, workers)
+	}
+	cnt := countBktBeg(bkt, hist)
+	ptr, dict, blk, rnk := makeCounters(bkt, hist, cnt)
+	n, p, end := 0, t.get(0), t.len()
+	b := bkt[p]
+	if ms == 0 || p != separator {
+		if t.len() > 1 && p > t.get(1) {
+			sa[b] = int32(^0)
+		} else {
+			sa[b] = int32(1)
+		}
+		b++
+	}
+	updateRank(rnk, p, 1)
+	for i, s32 := range sa {
+		s := int(s32)
+		if s == 0 {
+			continue
+		} else if s < 0 {
+			sa[i] = int32(^s)
+			continue
+		}
+		if s >= end {
+			sa[i] = int32(end - 1)
+			continue
+		}
+		n = t.get(s)
+		sa[i] = int32(^n)
+		if p != n {
+			bkt[p], b, p = b, bkt[n], n
+		}
+		if s+1 < end && p > t.get(s+1) {
+			sa[b] = int32(^s)
+		} else {
+			sa[b] = int32(s + 1)
+		}
+		b++
+	}
+	setBktEnd(bkt, hist)
+	p, b = 0, bkt[0]
+	l, idx := 0, cnt-1
+	for i := end - 1; i >= 0; i-- {
+		if i < ptr[idx] {
+			idx--
+		}
+		s := int(sa[i])
+		if s < 0 {
+			sa[i] = int32(^s)
+			updateRank(rnk, int(sa[i]), int(dict[idx]))
+			continue
+		}
+		s++
+		if s == end {
+			l = i
+			sa[i] = int32(0)
+			updateRank(rnk, 1, int(dict[idx]))
+			continue
+		}
+		n = t.get(s)
+		sa[i] = int32(n)
+		updateRank(rnk, n, int(dict[idx]))
+		if p != n {
+			bkt[p], b, p = b, bkt[n], n
+		}
+		b--
+		if b < ms {
+			continue
+		}
+		if s == end-1 || p < t.get(s+1) {
+			if s == end-1 {
+				l = b
+				sa[b] = int32(^0)
+			} else {
+				sa[b] = int32(^t.get(s + 1))
+			}
+		} else {
+			sa[b] = int32(s)
+		}
+	}
+	return l, blk, dict
+}
+
+func induceBWTBlock32(t buf, sa []int32, bkt, hist []int, ms, workers int) (int, []uint, []byte) {
+	cnt := countBktBeg(bkt, hist)
+	ptr, dict, blk, rnk := makeCounters(bkt, hist, cnt)
+	p, end := t.get(0), t.len()
+	b := bkt[p]
+	if ms == 0 || p != separator {
+		if t.len() > 1 && p > t.get(1) {
+			sa[b] = int32(^0)
+		} else {
+			sa[b] = int32(1)
+		}
+		b++
+	}
+	updateRank(rnk, p, 1)
+	c := newBlockScan(t, workers, func(i int) int {
+		return int(sa[i])
+	}, func(i, v int) {
+		sa[i] = int32(v)
+	})
+	c.scan(len(sa), 0, true, func(i int) {
+		s := c.get(i)
+		if s == 0 {
+			return
+		} else if s < 0 {
+			c.set(i, ^s)
+			return
+		}
+		if s >= end {
+			c.set(i, end-1)
+			return
+		}
+		n, x := c.sym(i, s)
+		c.set(i, ^n)
+		if p != n {
+			bkt[p], b, p = b, bkt[n], n
+		}
+		if s+1 < end && p > x {
+			c.set(b, ^s)
+		} else {
+			c.set(b, s+1)
+		}
+		b++
+	})
+	setBktEnd(bkt, hist)
+	p, b = 0, bkt[0]
+	l, idx := 0, cnt-1
+	c.scan(len(sa), 1, false, func(i int) {
+		if i < ptr[idx] {
+			idx--
+		}
+		s := c.get(i)
+		if s < 0 {
+			c.set(i, ^s)
+			updateRank(rnk, ^s, int(dict[idx]))
+			return
+		}
+		if s++; s == end {
+			l = i
+			c.set(i, 0)
+			updateRank(rnk, 1, int(dict[idx]))
+			return
+		}
+		n, x := c.sym(i, s)
+		c.set(i, n)
+		updateRank(rnk, n, int(dict[idx]))
+		if p != n {
+			bkt[p], b, p = b, bkt[n], n
+		}
+		if b--; b < ms {
+			return
+		}
+		if s == end-1 || p < x {
+			if s == end-1 {
+				l = b
+				c.set(b, ^0)
+			} else {
+				c.set(b, ^x)
+			}
+		} else {
+			c.set(b, s)
+		}
+	})
+	return l, blk, dict
+}
+
+func adjustLMS32(sa []int32, m int) {
+	for i := 0; i < m; i++ {
+		sa[i] = int32(0)
+	}
+	for i, j, end := m, 0, len(sa); i < end && j < m; i++ {
+		if int(sa[i]) > 0 {
+			if m+j != i {
+				sa[j+m] = int32(int(sa[i]) - 1)
+				sa[i] = int32(0)
+			} else {
+				sa[i]--
+			}
+			j++
+		}
+	}
+}
+
+func locateLMS32(t buf, sa []int32, m int, rec bool) {
+	p := t.get(0)
+	s := !rec && p == separator
+	for i, e := 1, t.len(); i < e; i++ {
+		c := t.get(i)
+		if s {
+			if p < c {
+				sa[m], s = int32(i-1), false
+				m++
+			}
+		} else if p > c {
+			s = true
+		}
+		p = c
+	}
+}
+
+func shuffleLMS32(sa []int32, m int) {
+	for i := 0; i < m; i++ {
+		j := m + int(sa[i])
+		sa[i], sa[j] = int32(int(sa[j])), int32(0)
+	}
+}
+
+func clearLMSLen32(sa []int32, m int) {
+	for i := m - 1; i >= 0; i-- {
+		sa[m+int(sa[i])>>1] = int32(0)
+	}
+}
+
+func restoreLMS32(t buf, sa []int32, bkt, hist []int, m, ms int) {
+	setBktEnd(bkt, hist)
+	p, b, i := 0, bkt[0], m-1
+	for ; i >= 0; i-- {
+		c := t.get(int(sa[i]))
+		if ms > 0 && c == separator {
+			break
+		}
+		if p != c {
+			bkt[p], b, p = b, bkt[c], c
+		}
+		b--
+		if b != i {
+			sa[b], sa[i] = int32(int(sa[i])+1), int32(0)
+		} else {
+			sa[i]++
+		}
+	}
+	if ms == 0 {
+		return
+	}
+	j, q := ms-1, t.len()-1
+	for ; t.get(q) == separator; q-- {
+		sa[j] = int32(^q)
+		j--
+	}
+	for ; i >= 0; i-- {
+		q = int(sa[i])
+		sa[j] = int32(q + 1)
+		for j--; q > 0 && t.get(q-1) == separator; j-- {
+			q--
+			sa[j] = int32(^q)
+		}
+	}
+}
+
+func findLMS32(t buf, sa []int32, bkt, hist []int, rec bool) (int, int) {
+	setBktEnd(bkt, hist)
+	m, ms, l, lms, sbkt := 0, 0, 0, -1, bkt[separator-1]
+	p := t.get(0)
+	s := !rec && p == separator
+	e := t.len()
+	for i := 1; i < e; i++ {
+		c := t.get(i)
+		if s && p < c {
+			m++
+			if !rec && p == separator {
+				sa[sbkt], lms = int32(i), -1
+				sbkt++
+				ms++
+			} else {
+				if lms >= 0 {
+					sa[lms] = int32(l)
+				}
+				bkt[p]--
+				lms, l = bkt[p], i
+			}
+			s = false
+		} else if p > c {
+			s = true
+		} else if !rec && p == separator {
+			sa[sbkt] = int32(^(i - 1))
+			sbkt++
+			ms++
+		}
+		p = c
+	}
+	if !rec && p == separator {
+		sa[sbkt] = int32(^(e - 1))
+		ms++
+	}
+	if m == 1 && lms >= 0 {
+		sa[lms] = int32(l)
+	}
+	return m, ms
+}
+
+func nameLMS32(t buf, sa []int32, m int, rec bool, workers int) int {
+	j := 0
+	for i, s32 := range sa {
+		s := int(s32)
+		if s < 0 {
+			sa[j] = int32(^s)
+			if j < i {
+				sa[i] = int32(0)
+			}
+			j++
+			if j == m {
+				break
+			}
+		}
+	}
+	p, j := t.get(0), 0
+	s := !rec && p == separator
+	for i, e := 1, t.len(); i < e; i++ {
+		n := t.get(i)
+		if s && p < n {
+			if !rec && p == separator {
+				sa[m+((i-1)>>1)] = int32(-1)
+			} else {
+				sa[m+((i-1)>>1)] = int32(i - j)
+			}
+			s, j = false, i-1
+		} else if p > n {
+			s = true
+		}
+		p = n
+	}
+	var diff []bool
+	if workers > 1 && m >= parMinLen {
+		diff = make([]bool, m)
+		parallel(workers, m-1, func(lo, hi int) {
+			for i := lo + 1; i <= hi; i++ {
+				x, y := int(sa[i-1]), int(sa[i])
+				diff[i] = diffLMS(t, x, y, int(sa[m+x>>1]), int(sa[m+y>>1]), rec)
+			}
+		})
+	}
+	plen, b, n := -1, int(sa[0])>>1, 1
+	plen, sa[m+b] = int(sa[m+b]), int32(n)
+	for i := 1; i < m; i++ {
+		b = int(sa[i]) >> 1
+		if diff != nil && diff[i] || diff == nil && diffLMS(t, int(sa[i-1]), int(sa[i]), plen, int(sa[m+b]), rec) {
+			n++
+		}
+		plen, sa[m+b] = int(sa[m+b]), int32(n)
+	}
+	return n
+}
+
+func sortLMS32(t buf, sa []int32, bkt, hist []int, ms, workers int) {
+	if workers > 1 && t.len() >= parMinLen {
+		sortLMSBlock32(t, sa, bkt, hist, ms, workers)
+		return
+	}
+	setBktBeg(bkt, hist)
+	p, sz := t.get(0), t.len()
+	b := bkt[p]
+	if ms == 0 || p != separator {
+		if p > t.get(1) {
+			sa[b] = int32(^1)
+		} else {
+			sa[b] = int32(1)
+		}
+		b++
+	}
+	for i, s32 := range sa {
+		s := int(s32)
+		if s > 0 {
+			c := t.get(s)
+			if p != c {
+				bkt[p], b, p = b, bkt[c], c
+			}
+			s++
+			if s >= sz {
+				sa[b] = int32(0)
+			} else if p > t.get(s) {
+				sa[b] = int32(^s)
+			} else {
+				sa[b] = int32(s)
+			}
+			b++
+			if i >= ms {
+				sa[i] = int32(0)
+			} else {
+				sa[i] = int32(^(int(sa[i]) - 1))
+			}
+		} else if s < 0 {
+			sa[i] = int32(^s)
+		}
+	}
+	setBktEnd(bkt, hist)
+	b, p = bkt[0], 0
+	for i := len(sa) - 1; i >= 0; i-- {
+		s := int(sa[i])
+		if s > 0 {
+			c := t.get(s)
+			if p != c {
+				bkt[p], b, p = b, bkt[c], c
+			}
+			b--
+			s++
+			sa[i] = int32(0)
+			if b < ms {
+				continue
+			}
+			if s >= sz {
+				sa[b] = int32(0)
+			} else if p < t.get(s) {
+				sa[b] = int32(^(s - 1))
+			} else {
+				sa[b] = int32(s)
+			}
+		}
+	}
+}
+
+func sortLMSBlock32(t buf, sa []int32, bkt, hist []int, ms, workers int) {
+	setBktBeg(bkt, hist)
+	p, sz := t.get(0), t.len()
+	b := bkt[p]
+	if ms == 0 || p != separator {
+		if p > t.get(1) {
+			sa[b] = int32(^1)
+		} else {
+			sa[b] = int32(1)
+		}
+		b++
+	}
+	c := newBlockScan(t, workers, func(i int) int {
+		return int(sa[i])
+	}, func(i, v int) {
+		sa[i] = int32(v)
+	})
+	c.scan(len(sa), 0, true, func(i int) {
+		s := c.get(i)
+		if s < 0 {
+			c.set(i, ^s)
+			return
+		} else if s == 0 {
+			return
+		}
+		n, x := c.sym(i, s)
+		if p != n {
+			bkt[p], b, p = b, bkt[n], n
+		}
+		if s++; s >= sz {
+			c.set(b, 0)
+		} else if p > x {
+			c.set(b, ^s)
+		} else {
+			c.set(b, s)
+		}
+		b++
+		if i >= ms {
+			c.set(i, 0)
+		} else {
+			c.set(i, ^(c.get(i) - 1))
+		}
+	})
+	setBktEnd(bkt, hist)
+	b, p = bkt[0], 0
+	c.scan(len(sa), 0, false, func(i int) {
+		s := c.get(i)
+		if s <= 0 {
+			return
+		}
+		n, x := c.sym(i, s)
+		if p != n {
+			bkt[p], b, p = b, bkt[n], n
+		}
+		c.set(i, 0)
+		if b--; b < ms {
+			return
+		}
+		if s++; s >= sz {
+			c.set(b, 0)
+		} else if p < x {
+			c.set(b, ^(s - 1))
+		} else {
+			c.set(b, s)
+		}
+	})
+}
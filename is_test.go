@@ -1,50 +1,24 @@
 package sa
 
 import (
+	"bytes"
 	"flag"
 	"fmt"
 	"io/ioutil"
 	"log"
+	"math/rand"
 	"reflect"
+	"sort"
 	"testing"
 	"time"
 )
 
 var datafile string
+var large bool
 
 func init() {
 	flag.StringVar(&datafile, "file", "", "testing data file")
-}
-
-func count(bwt []byte, c byte, o, l int) int {
-	j := 0
-	for i := 0; i <= o; i++ {
-		if bwt[i] == c {
-			j++
-		}
-	}
-
-	return j
-}
-
-func rewindBWT(bwt []byte, l int) []byte {
-	hist, bkt := histgram(bytebuf(bwt), 256)
-	setBktBeg(bkt, hist)
-
-	t := make([]byte, len(bwt)-1)
-
-	for i, j := 0, 0; bwt[i] != 0; j++ {
-		t[j] = bwt[i]
-		if t[j] == 1 {
-			i = bkt[1]
-			bkt[1]++
-		} else {
-			c := count(bwt, bwt[i], i, l)
-			i = bkt[bwt[i]] + c - 1
-		}
-	}
-
-	return t
+	flag.BoolVar(&large, "large", false, "test text larger than 2^31 bytes, needs about 24 GB memory")
 }
 
 func toString(b []byte, o, r byte) string {
@@ -109,13 +83,85 @@ func Test_bwt(t *testing.T) {
 		a := make([]byte, len(tt.args.t))
 		copy(a, tt.args.t)
 		t.Run(tt.name, func(t *testing.T) {
-			if got, b, _ := BWT(tt.args.t); !reflect.DeepEqual(a, rewindBWT(b, got)) {
-				t.Errorf("bwt() = %v, %v, want %v, %v", got, b, a, toString(rewindBWT(b, got), 1, '$'))
+			if got, b, _ := BWT(tt.args.t); !reflect.DeepEqual(a, InverseBWT(b, got)) {
+				t.Errorf("bwt() = %v, %v, want %v, %v", got, b, a, toString(InverseBWT(b, got), 1, '$'))
 			}
 		})
 	}
 }
 
+func TestInverseBWT(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	for i := 0; i < 2000; i++ {
+		a := randText(r, 2+r.Intn(60), 1+r.Intn(4), i%2 == 0)
+		l, b, _ := BWT(append([]byte{}, a...))
+		if b[l] != 0 {
+			t.Fatalf("BWT(%q) = %v, %v, row %d is not end of text", toString(a, 1, '$'), l, b, l)
+		}
+		if got := InverseBWT(b, l); !reflect.DeepEqual(got, a) {
+			t.Fatalf("InverseBWT(%v, %v) = %q, want %q", b, l, toString(got, 1, '$'), toString(a, 1, '$'))
+		}
+	}
+
+	if got := InverseBWT(nil, 0); got != nil {
+		t.Errorf("InverseBWT(nil, 0) = %v, want nil", got)
+	}
+	for _, l := range []int{-1, 3} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("InverseBWT() of row %d did not panic", l)
+				}
+			}()
+			InverseBWT([]byte{'a', 0, 'b'}, l)
+		}()
+	}
+
+	// binary BWT does not end at row l, LF mapping cycles without the end of text
+	_, bin, _ := (&Options{Binary: true}).BWT([]byte{0, 1, 5, 0, 1, 1, 7, 0})
+	for l := 1; l <= 5; l++ {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("InverseBWT() of binary BWT at row %d did not panic", l)
+				}
+			}()
+			InverseBWT(bin, l)
+		}()
+	}
+}
+
+func TestRotationBWT(t *testing.T) {
+	r := rand.New(rand.NewSource(12))
+	for i := 0; i < 200; i++ {
+		text := make([]byte, r.Intn(300))
+		k := 1 + r.Intn(256)
+		for j := range text {
+			text[j] = byte(r.Intn(k))
+		}
+		if i%10 == 0 && len(text) > 0 {
+			// periodic text has equal rotations
+			text = bytes.Repeat(text[:1+len(text)%7], 5)
+		}
+
+		rots := make([]string, len(text))
+		for j := range text {
+			rots[j] = string(text[j:]) + string(text[:j])
+		}
+		sort.Strings(rots)
+
+		l, bwt := RotationBWT(text)
+		if len(text) > 0 && rots[l] != string(text) {
+			t.Fatalf("RotationBWT(%v) row %d = %v", text, l, []byte(rots[l]))
+		}
+		for j, rot := range rots {
+			if bwt[j] != rot[len(rot)-1] {
+				t.Fatalf("RotationBWT(%v) = %v", text, bwt)
+			}
+		}
+	}
+}
+
 func Test_sais(t *testing.T) {
 	type args struct {
 		t []byte
@@ -137,13 +183,541 @@ func Test_sais(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			sa := make([]int, len(tt.args.t))
-			if sais(bytebuf(tt.args.t), sa, 256, false, false); !reflect.DeepEqual(sa, tt.want) {
+			if sais(bytebuf(tt.args.t), sa, 256, false, false, 1); !reflect.DeepEqual(sa, tt.want) {
 				t.Errorf("bwt() = %v, want %v", sa, tt.want)
 			}
 		})
 	}
 }
 
+// naiveSA sorts positions by comparing the text backwards, separators compare by their positions
+func naiveSA(t []byte) []int {
+	return naiveSASep(t, separator)
+}
+
+// naiveSASep sorts positions as naiveSA does, strings are divided by sep
+func naiveSASep(t []byte, sep byte) []int {
+	sa := make([]int, len(t))
+	for i := range sa {
+		sa[i] = i
+	}
+
+	less := func(x, y int) bool {
+		for ; ; x, y = x-1, y-1 {
+			xs, ys := x < 0 || t[x] == sep, y < 0 || t[y] == sep
+			if xs || ys {
+				if xs && ys {
+					return x < y
+				}
+				return xs
+			}
+			if t[x] != t[y] {
+				return t[x] < t[y]
+			}
+		}
+	}
+	sort.Slice(sa, func(i, j int) bool { return less(sa[i], sa[j]) })
+
+	return sa
+}
+
+// randText generates text of alphabet 'a' to 'a' + k - 1, separated by byte value (1) with no empty string
+func randText(r *rand.Rand, n, k int, sep bool) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		if sep && i > 0 && i < n-1 && b[i-1] != separator && r.Intn(5) == 0 {
+			b[i] = separator
+		} else {
+			b[i] = byte('a' + r.Intn(k))
+		}
+	}
+
+	return b
+}
+
+func TestSuffixArray(t *testing.T) {
+	tests := []struct {
+		name string
+		t    []byte
+		want []int
+	}{
+		{"empty", []byte{}, []int{}},
+		{"one", []byte("a"), []int{0}},
+		{"two", []byte("ba"), []int{1, 0}},
+		{"abcabca", []byte("abcabca"), []int{0, 3, 6, 1, 4, 2, 5}},
+		{"separators", toByte("bbaab$aca$bccbc", '$', 1), []int{5, 9, 6, 3, 2, 8, 0, 10, 4, 1, 13, 7, 11, 14, 12}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SuffixArray(tt.t); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SuffixArray() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	r := rand.New(rand.NewSource(1))
+	sa := make([]int, 64)
+	for i := 0; i < 2000; i++ {
+		b := randText(r, 2+r.Intn(60), 1+r.Intn(4), i%2 == 0)
+		for j := range sa {
+			sa[j] = -1
+		}
+		if SuffixArrayTo(b, sa); !reflect.DeepEqual(sa[:len(b)], naiveSA(b)) {
+			t.Fatalf("SuffixArrayTo(%q) = %v, want %v", toString(b, 1, '$'), sa[:len(b)], naiveSA(b))
+		}
+	}
+}
+
+func TestEmptyStrings(t *testing.T) {
+	tests := []struct {
+		name string
+		t    string
+		want []int
+	}{
+		{"separator", "$", []int{0}},
+		{"leading", "$ab", []int{0, 1, 2}},
+		{"trailing", "ab$", []int{2, 0, 1}},
+		{"consecutive", "ba$$ab", []int{2, 3, 4, 1, 0, 5}},
+		{"separators", "$$$", []int{0, 1, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := toByte(tt.t, '$', 1)
+			if got := SuffixArray(b); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SuffixArray() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if l, b, _ := BWT(nil); l != 0 || !bytes.Equal(b, []byte{0}) || len(InverseBWT(b, l)) != 0 {
+		t.Errorf("BWT() of empty text = %d, %v, want 0, [0]", l, b)
+	}
+
+	// strings of random length including empty ones, runs of separators are sorted in place by sais
+	r := rand.New(rand.NewSource(21))
+	for i := 0; i < 2000; i++ {
+		b, sep := make([]byte, r.Intn(40)), 2+r.Intn(3)
+		if i%100 == 0 {
+			b = make([]byte, r.Intn(1000))
+		}
+		for j := range b {
+			if r.Intn(sep) == 0 {
+				b[j] = separator
+			} else {
+				b[j] = byte('a' + r.Intn(3))
+			}
+		}
+		s := toString(append([]byte{}, b...), 1, '$')
+
+		want := naiveSA(b)
+		if got := SuffixArray(b); !reflect.DeepEqual(got, want) {
+			t.Fatalf("SuffixArray(%q) = %v, want %v", s, got, want)
+		}
+		for j, p := range SuffixArray32(b) {
+			if int(p) != want[j] {
+				t.Fatalf("SuffixArray32(%q) = %v, want %v", s, SuffixArray32(b), want)
+			}
+		}
+
+		l, bwt64, aux64 := bwt(append([]byte{}, b...), true, 1)
+		l32, bwt32, aux32 := bwt(append([]byte{}, b...), false, 1)
+		if l != l32 || !bytes.Equal(bwt64, bwt32) || !reflect.DeepEqual(aux64, aux32) {
+			t.Fatalf("bwt(%q) of int32 = %d, %v, want %d, %v", s, l32, bwt32, l, bwt64)
+		}
+		if got := newAux(bwt64, rankBWT(bwt64), dictBWT(bwt64)); !reflect.DeepEqual(got, aux64) {
+			t.Fatalf("Aux of bwt(%q) = %v, want %v", s, aux64, got)
+		}
+		if got := InverseBWT(bwt64, l); !bytes.Equal(got, b) {
+			t.Fatalf("InverseBWT(BWT(%q)) = %q", s, toString(got, 1, '$'))
+		}
+		if got := NewFMIndex(bwt64, aux64).Text(); !bytes.Equal(got, b) {
+			t.Fatalf("Text() of %q = %q", s, toString(got, 1, '$'))
+		}
+	}
+}
+
+func TestBinary(t *testing.T) {
+	// naive suffix array of one string of any byte, compared backwards
+	naive := func(t []byte) []int {
+		sa := make([]int, len(t))
+		for i := range sa {
+			sa[i] = i
+		}
+		sort.Slice(sa, func(i, j int) bool {
+			x, y := sa[i], sa[j]
+			for ; x >= 0 && y >= 0 && t[x] == t[y]; x, y = x-1, y-1 {
+			}
+			return x < 0 || y >= 0 && t[x] < t[y]
+		})
+		return sa
+	}
+
+	o := &Options{Binary: true}
+	r := rand.New(rand.NewSource(22))
+	for i := 0; i < 1000; i++ {
+		b := make([]byte, r.Intn(50))
+		for j := range b {
+			// mostly reserved bytes
+			b[j] = byte(r.Intn(4))
+			if r.Intn(4) == 0 {
+				b[j] = byte(r.Intn(256))
+			}
+		}
+
+		want := naive(b)
+		if got := o.SuffixArray(b); !reflect.DeepEqual(got, want) {
+			t.Fatalf("SuffixArray(%v) = %v, want %v", b, got, want)
+		}
+		for j, p := range o.SuffixArray32(b) {
+			if int(p) != want[j] {
+				t.Fatalf("SuffixArray32(%v) = %v, want %v", b, o.SuffixArray32(b), want)
+			}
+		}
+
+		l, bwt64 := bwtBinary(append([]byte{}, b...), true, 1)
+		l32, bwt32, aux := o.BWT(append([]byte{}, b...))
+		if l != l32 || !bytes.Equal(bwt64, bwt32) || aux == nil || !aux.Binary || aux.End != uint(l) {
+			t.Fatalf("BWT(%v) of int32 = %d, %v, want %d, %v", b, l32, bwt32, l, bwt64)
+		}
+		if got := o.InverseBWT(bwt32, l32); !bytes.Equal(got, b) {
+			t.Fatalf("InverseBWT(BWT(%v)) = %v", b, got)
+		}
+	}
+
+	// same as BWT of text without reserved bytes
+	for i := 0; i < 100; i++ {
+		b := randText(r, 1+r.Intn(50), 1+r.Intn(4), false)
+		l, bwt, _ := BWT(append([]byte{}, b...))
+		if bl, bb, _ := o.BWT(append([]byte{}, b...)); bl != l || !bytes.Equal(bb, bwt) {
+			t.Fatalf("BWT(%q) = %d, %v, want %d, %v", b, bl, bb, l, bwt)
+		}
+	}
+}
+
+func TestSeparator(t *testing.T) {
+	r := rand.New(rand.NewSource(23))
+	for _, o := range []*Options{{Separator: '\n'}, {Separator: '\n', Sentinel: '#'}, {Separator: 'b', Sentinel: 1}} {
+		sep := o.Separator
+		for i := 0; i < 500; i++ {
+			// any byte but sentinel, eg, (1) if sentinel is not (1)
+			b := make([]byte, r.Intn(40))
+			for j := range b {
+				switch k := r.Intn(6); {
+				case k == 0:
+					b[j] = sep
+				case k == 1 && o.Sentinel != 1:
+					b[j] = 1
+				default:
+					b[j] = byte('a' + r.Intn(4))
+				}
+			}
+			s := fmt.Sprintf("%q of %q", b, sep)
+
+			want := naiveSASep(b, sep)
+			if got := o.SuffixArray(b); !reflect.DeepEqual(got, want) {
+				t.Fatalf("SuffixArray(%s) = %v, want %v", s, got, want)
+			}
+			for j, p := range o.SuffixArray32(b) {
+				if int(p) != want[j] {
+					t.Fatalf("SuffixArray32(%s) = %v, want %v", s, o.SuffixArray32(b), want)
+				}
+			}
+
+			l, bwt, aux := o.BWT(append([]byte{}, b...))
+			if bytes.IndexByte(bwt[1:], o.Sentinel) != l-1 {
+				t.Fatalf("BWT(%s) = %d, %q, want sentinel at row %d", s, l, bwt, l)
+			}
+			if aux.Separator != sep || aux.Sentinel != o.Sentinel {
+				t.Fatalf("BWT(%s) Aux of %q, %q, want %q, %q", s, aux.Separator, aux.Sentinel, sep, o.Sentinel)
+			}
+			tr := append([]byte{}, bwt...)
+			translate(tr, o.alphabet())
+			if want := newAux(tr, rankBWT(tr), dictBWT(tr)); !reflect.DeepEqual(aux.Eob, want.Eob) ||
+				!reflect.DeepEqual(aux.Hist, want.Hist) || !bytes.Equal(aux.Dict, want.Dict) {
+				t.Fatalf("BWT(%s) Aux = %v, want %v", s, aux, want)
+			}
+			if got := o.InverseBWT(bwt, l); !bytes.Equal(got, b) {
+				t.Fatalf("InverseBWT(BWT(%s)) = %q", s, got)
+			}
+		}
+	}
+
+	// same as BWT of lines divided by (1)
+	lines := []byte("sisisim\n\nsisisim\nanana\n")
+	l, bwt, _ := BWT(bytes.ReplaceAll(lines, []byte{'\n'}, []byte{separator}))
+	if nl, nbwt, _ := (&Options{Separator: '\n'}).BWT(append([]byte{}, lines...)); nl != l ||
+		!bytes.Equal(nbwt, bytes.ReplaceAll(bwt, []byte{separator}, []byte{'\n'})) {
+		t.Errorf("BWT(%q) = %d, %q, want %d, %q", lines, nl, nbwt, l, bwt)
+	}
+}
+
+func TestStandard(t *testing.T) {
+	o := &Options{Standard: true}
+	if got, want := o.SuffixArray([]byte("banana")), []int{5, 3, 1, 0, 4, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("SuffixArray(banana) = %v, want %v", got, want)
+	}
+	if l, bwt, _ := o.BWT([]byte("banana")); l != 4 || string(bwt) != "annbaa" {
+		t.Errorf("BWT(banana) = %d, %q, want 4, annbaa", l, bwt)
+	}
+
+	r := rand.New(rand.NewSource(24))
+	for i := 0; i < 1000; i++ {
+		b := make([]byte, r.Intn(50))
+		for j := range b {
+			b[j] = byte(r.Intn(4))
+		}
+
+		// suffixes of t$ sorted forward, BWT is the byte before each suffix without $
+		want := make([]int, len(b))
+		for j := range want {
+			want[j] = j
+		}
+		sort.Slice(want, func(x, y int) bool { return bytes.Compare(b[want[x]:], b[want[y]:]) < 0 })
+		var wbwt []byte
+		wl := 0
+		if len(b) > 0 {
+			wbwt = append(wbwt, b[len(b)-1])
+		}
+		for j, p := range want {
+			if p == 0 {
+				wl = j + 1
+			} else {
+				wbwt = append(wbwt, b[p-1])
+			}
+		}
+
+		if got := o.SuffixArray(b); !reflect.DeepEqual(got, want) {
+			t.Fatalf("SuffixArray(%v) = %v, want %v", b, got, want)
+		}
+		for j, p := range o.SuffixArray32(b) {
+			if int(p) != want[j] {
+				t.Fatalf("SuffixArray32(%v) = %v, want %v", b, o.SuffixArray32(b), want)
+			}
+		}
+
+		l, bwt64 := bwtStandard(append([]byte{}, b...), true, 1)
+		l32, bwt32, aux := o.BWT(append([]byte{}, b...))
+		if l != wl || !bytes.Equal(bwt64, wbwt) || l32 != wl || !bytes.Equal(bwt32, wbwt) || aux != nil {
+			t.Fatalf("BWT(%v) = %d, %v, want %d, %v", b, l32, bwt32, wl, wbwt)
+		}
+		if got := o.InverseBWT(bwt32, l32); !bytes.Equal(got, b) {
+			t.Fatalf("InverseBWT(BWT(%v)) = %v", b, got)
+		}
+	}
+}
+
+func TestBWTInPlace(t *testing.T) {
+	text := []byte("sisisim\nsisisim\n\nanana")
+	for _, o := range []*Options{nil, {Binary: true}, {Separator: '\n'}, {Standard: true}} {
+		// copy leaves text untouched
+		a := append([]byte{}, text...)
+		l, bwt, _ := o.BWT(a)
+		if !bytes.Equal(a, text) {
+			t.Fatalf("BWT() of %+v modified text to %q", o, a)
+		}
+
+		// in place shares the array of text
+		p := &Options{InPlace: true}
+		if o != nil {
+			x := *o
+			x.InPlace, p = true, &x
+		}
+		n := len(text) + 1
+		if p.Standard {
+			n = len(text)
+		}
+		b := append(make([]byte, 0, n), text...)
+		pl, pbwt, _ := p.BWT(b)
+		if pl != l || !bytes.Equal(pbwt, bwt) || &pbwt[0] != &b[0] {
+			t.Errorf("BWT() in place of %+v = %d, %q, want %d, %q in the same array", o, pl, pbwt, l, bwt)
+		}
+
+		if p.Standard {
+			// BWT is as long as text
+			continue
+		}
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("BWT() in place of %+v without capacity did not panic", o)
+				}
+			}()
+			c := append([]byte{}, text...)
+			p.BWT(c[:len(c):len(c)])
+		}()
+
+		// empty text has nothing to overwrite
+		if pl, pbwt, _ := p.BWT(nil); !bytes.Equal(p.InverseBWT(pbwt, pl), nil) {
+			t.Errorf("BWT() in place of %+v of empty text = %d, %v", o, pl, pbwt)
+		}
+	}
+}
+
+func TestSuffixArray32(t *testing.T) {
+	r := rand.New(rand.NewSource(18))
+	for i := 0; i < 500; i++ {
+		b := randText(r, r.Intn(3000), 1+r.Intn(60), i%2 == 0)
+		want, got := SuffixArray(b), SuffixArray32(b)
+		if len(got) != len(want) {
+			t.Fatalf("SuffixArray32(%q) = %v, want %v", toString(b, 1, '$'), got, want)
+		}
+		for j, p := range want {
+			if int(got[j]) != p {
+				t.Fatalf("SuffixArray32(%q) = %v, want %v", toString(b, 1, '$'), got, want)
+			}
+		}
+
+		if len(b) == 0 {
+			continue
+		}
+		l, bwt64, aux64 := bwt(append([]byte{}, b...), true, 1)
+		l32, bwt32, aux32 := bwt(append([]byte{}, b...), false, 1)
+		if l != l32 || !reflect.DeepEqual(bwt64, bwt32) || !reflect.DeepEqual(aux64, aux32) {
+			t.Fatalf("bwt(%q) of int32 = %d, %v, want %d, %v", toString(b, 1, '$'), l32, bwt32, l, bwt64)
+		}
+	}
+}
+
+func TestWide(t *testing.T) {
+	// int suffix array of texts of 2^31 bytes or more, forced on short texts, same as int32
+	r := rand.New(rand.NewSource(21))
+	for i := 0; i < 300; i++ {
+		b := randText(r, 1+r.Intn(2000), 1+r.Intn(4), i%2 == 0)
+		if i%3 == 0 {
+			// repeated blocks recurse on LMS names, empty strings by consecutive separators
+			b = bytes.Repeat(b[:1+len(b)%13], 1+r.Intn(100))
+			for k := r.Intn(4); k > 0; k-- {
+				b[r.Intn(len(b))] = separator
+			}
+		}
+
+		l, bwt64, aux64 := bwt(append([]byte{}, b...), true, 1)
+		l32, bwt32, aux32 := bwt(append([]byte{}, b...), false, 1)
+		if l != l32 || !bytes.Equal(bwt64, bwt32) || !reflect.DeepEqual(aux64, aux32) {
+			t.Fatalf("bwt(%q) of int = %d, %v, want %d, %v", toString(b, 1, '$'), l, bwt64, l32, bwt32)
+		}
+		l, bwt64 = bwtBinary(append([]byte{}, b...), true, 1)
+		l32, bwt32 = bwtBinary(append([]byte{}, b...), false, 1)
+		if l != l32 || !bytes.Equal(bwt64, bwt32) {
+			t.Fatalf("bwtBinary(%v) of int = %d, %v, want %d, %v", b, l, bwt64, l32, bwt32)
+		}
+		l, bwt64 = bwtStandard(append([]byte{}, b...), true, 1)
+		l32, bwt32 = bwtStandard(append([]byte{}, b...), false, 1)
+		if l != l32 || !bytes.Equal(bwt64, bwt32) {
+			t.Fatalf("bwtStandard(%v) of int = %d, %v, want %d, %v", b, l, bwt64, l32, bwt32)
+		}
+	}
+}
+
+// logLines generates n bytes of repetitive lines divided by separator
+func logLines(r *rand.Rand, n int) []byte {
+	words := [][]byte{[]byte("error"), []byte("warn"), []byte("info"), []byte("disk"), []byte("full"), []byte("ok")}
+	b := make([]byte, 0, n+64)
+	for len(b) < n {
+		for k := 1 + r.Intn(8); k > 0; k-- {
+			b = append(b, words[r.Intn(len(words))]...)
+			b = append(b, ' ')
+		}
+		b = append(b, byte('0'+r.Intn(10)), separator)
+	}
+	return b[:len(b)-1]
+}
+
+func TestWorkers(t *testing.T) {
+	// repetitive lines, long enough to induce and name LMS substrings in parallel
+	b := logLines(rand.New(rand.NewSource(20)), 4<<20)
+
+	seq, par := &Options{}, &Options{Workers: 4}
+	if !reflect.DeepEqual(seq.SuffixArray(b), par.SuffixArray(b)) {
+		t.Errorf("SuffixArray() of 4 workers differs")
+	}
+	if !reflect.DeepEqual(seq.SuffixArray32(b), par.SuffixArray32(b)) {
+		t.Errorf("SuffixArray32() of 4 workers differs")
+	}
+	l, bwt, aux := seq.BWT(append([]byte{}, b...))
+	pl, pbwt, paux := par.BWT(append([]byte{}, b...))
+	if l != pl || !bytes.Equal(bwt, pbwt) || !reflect.DeepEqual(aux, paux) {
+		t.Errorf("BWT() of 4 workers = %d, want %d", pl, l)
+	}
+	if !bytes.Equal(InverseBWT(pbwt, pl), b) {
+		t.Errorf("InverseBWT() of 4 workers differs from text")
+	}
+}
+
+func BenchmarkWorkers(b *testing.B) {
+	// compare the number of workers on a multi-core machine, go test -run NONE -bench Workers
+	text := logLines(rand.New(rand.NewSource(20)), 8<<20)
+	for _, w := range []int{1, 2, 4} {
+		o := &Options{Workers: w}
+		b.Run(fmt.Sprintf("SuffixArray32/%d", w), func(b *testing.B) {
+			sa := make([]int32, len(text))
+			b.SetBytes(int64(len(text)))
+			for i := 0; i < b.N; i++ {
+				o.SuffixArrayTo32(text, sa)
+			}
+		})
+		b.Run(fmt.Sprintf("BWT/%d", w), func(b *testing.B) {
+			b.SetBytes(int64(len(text)))
+			for i := 0; i < b.N; i++ {
+				o.BWT(text)
+			}
+		})
+	}
+}
+
+func TestLargeText(t *testing.T) {
+	if !large {
+		t.Skip("skipping text larger than 2^31 bytes, -large option is false")
+	}
+
+	if ^uint(0)>>32 == 0 {
+		t.Skip("skipping text larger than 2^31 bytes on 32 bit platform")
+	}
+
+	// note: 1<<31 does not compile as int on 32 bit platforms
+	h := 1 << 30
+	h *= 2
+	n := h + 1<<20
+
+	r := rand.New(rand.NewSource(19))
+	text := make([]byte, n)
+	for i := range text {
+		text[i] = byte('a' + r.Intn(4))
+		if i%(1<<20) == 1<<19 {
+			text[i] = separator
+		}
+	}
+	// patterns across 2^31, and random ones without separator
+	patterns := [][]byte{append([]byte{}, text[h-6:h+6]...)}
+	for len(patterns) < 6 {
+		p := text[r.Intn(n-12):][:12]
+		if bytes.IndexByte(p, separator) < 0 {
+			patterns = append(patterns, append([]byte{}, p...))
+		}
+	}
+	counts := make([]int, len(patterns))
+	for k, p := range patterns {
+		for i := 0; ; i++ {
+			j := bytes.Index(text[i:], p)
+			if j < 0 {
+				break
+			}
+			counts[k], i = counts[k]+1, i+j
+		}
+	}
+
+	_, bwt, aux := BWT(text)
+	f := NewFMIndex(bwt, aux)
+	if f.Len() != n {
+		t.Fatalf("Len() = %d, want %d", f.Len(), n)
+	}
+	for k, p := range patterns {
+		if got := f.Count(p); got != counts[k] {
+			t.Errorf("Count(%q) = %d, want %d", p, got, counts[k])
+		}
+	}
+}
+
 func Test_readfile(t *testing.T) {
 	if datafile == "" {
 		t.Skip("skiping readfile, -file option is empty")
@@ -152,22 +726,8 @@ func Test_readfile(t *testing.T) {
 	if err != nil {
 		log.Fatal(err)
 	}
-	s := 0
-	for _, b := range d {
-		if b == '\n' {
-			if s > 0 && d[s-1] != 1 {
-				d[s] = 1
-				s++
-			}
-			continue
-		}
-		d[s] = b
-		s++
-	}
-	if d[s-1] == 1 {
-		s--
-	}
-	d = d[:s]
+	// lines are divided by '\n', no need to replace it by (1)
+	o := &Options{Separator: '\n'}
 
 	type args struct {
 		t []byte
@@ -185,10 +745,10 @@ func Test_readfile(t *testing.T) {
 			copy(a, tt.args.t)
 			fmt.Printf("starting\n")
 			s := time.Now()
-			got, b, _ := BWT(tt.args.t)
+			got, b, _ := o.BWT(tt.args.t)
 			fmt.Printf("done in %v\n", time.Since(s))
-			if !reflect.DeepEqual(a, rewindBWT(b, got)) {
-				t.Errorf("bwt() = %v, %v, want %v", got, rewindBWT(b, got), len(a))
+			if !reflect.DeepEqual(a, o.InverseBWT(b, got)) {
+				t.Errorf("bwt() = %v, %v, want %v", got, o.InverseBWT(b, got), len(a))
 			} else {
 				freeq := map[byte]int{}
 				sz := 0
@@ -0,0 +1,115 @@
+/*
+ * Copyright 2020 Rock Lei Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sa
+
+// SuffixArrayLCP returns the suffix array of t and its longest common prefix array,
+// lcp[i] is the length of the common prefix of sa[i-1] and sa[i], lcp[0] is 0.
+// Note: prefix is read backwards, same as SuffixArray, separators never match.
+//
+// LCP is computed after sorting by the phi algorithm (Karkkainen, Manzini and Puglisi), not induced along
+// with the suffix array: inducing LCP (Fischer) needs a range minimum query for every induced suffix,
+// which would slow down the induction scans of every caller, including the parallel blocks of
+// Options.Workers. The phi pass is linear, it needs no memory but the lcp array and n bits.
+func SuffixArrayLCP(t []byte) ([]int, []int) {
+	return (*Options)(nil).SuffixArrayLCP(t)
+}
+
+// SuffixArrayLCP returns the suffix array and longest common prefix array with options, see SuffixArrayLCP.
+// Common prefixes stop at Separator, there is no separator if Binary or Standard, prefix is read forward
+// if Standard.
+func (o *Options) SuffixArrayLCP(t []byte) ([]int, []int) {
+	sa := o.SuffixArray(t)
+	return sa, o.LCP(t, sa)
+}
+
+// LCP returns the longest common prefix array of t and its suffix array sa
+func LCP(t []byte, sa []int) []int {
+	return (*Options)(nil).LCP(t, sa)
+}
+
+// LCP returns the longest common prefix array of t and its suffix array sa with options, see SuffixArrayLCP
+func (o *Options) LCP(t []byte, sa []int) []int {
+	lcp := make([]int, len(sa))
+	if len(sa) == 0 {
+		return lcp
+	}
+
+	// phi[sa[i]] -> sa[i-1], the position sorted right before sa[i], in place of lcp
+	phi := lcp
+	phi[sa[0]] = -1
+	for i := 1; i < len(sa); i++ {
+		phi[sa[i]] = sa[i-1]
+	}
+
+	// no separator matches -1
+	sep := int(o.separator())
+	if !o.divided() {
+		sep = -1
+	}
+
+	if o.standard() {
+		// permuted lcp read forward, plcp[p] >= plcp[p-1] - 1, scan from the start of text
+		for p, h := 0, 0; p < len(t); p++ {
+			q := phi[p]
+			if q < 0 {
+				h = 0
+			} else {
+				for p+h < len(t) && q+h < len(t) && t[p+h] == t[q+h] {
+					h++
+				}
+			}
+			phi[p] = h
+			if h > 0 {
+				h--
+			}
+		}
+	} else {
+		// permuted lcp, plcp[p] >= plcp[p+1] - 1, position p - 1 follows position p when reading backwards,
+		// scan from the end of text so h decreases at most by one every step, plcp overwrites phi
+		for p, h := len(t)-1, 0; p >= 0; p-- {
+			q := phi[p]
+			if q < 0 {
+				h = 0
+			} else {
+				for h <= p && h <= q && t[p-h] == t[q-h] && int(t[p-h]) != sep {
+					h++
+				}
+			}
+			phi[p] = h
+			if h > 0 {
+				h--
+			}
+		}
+	}
+
+	// lcp[i] = plcp[sa[i]], permute in place by following the cycles of sa, done marks written rows
+	done := newBitvec(len(sa))
+	for i := range sa {
+		if done.get(i) {
+			continue
+		}
+		h, j := lcp[i], i
+		for ; sa[j] != i; j = sa[j] {
+			lcp[j] = lcp[sa[j]]
+			done.set(j)
+		}
+		lcp[j] = h
+		done.set(j)
+	}
+
+	return lcp
+}
@@ -0,0 +1,77 @@
+package sa
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func naiveLCP(t []byte, sa []int) []int {
+	lcp := make([]int, len(sa))
+	for i := 1; i < len(sa); i++ {
+		x, y := sa[i-1], sa[i]
+		for x >= 0 && y >= 0 && t[x] == t[y] && t[x] != separator {
+			lcp[i]++
+			x--
+			y--
+		}
+	}
+
+	return lcp
+}
+
+func TestSuffixArrayLCP(t *testing.T) {
+	tests := []struct {
+		name    string
+		t       []byte
+		wantSA  []int
+		wantLCP []int
+	}{
+		{"empty", []byte{}, []int{}, []int{}},
+		{"one", []byte("a"), []int{0}, []int{0}},
+		{"abcabca", []byte("abcabca"), []int{0, 3, 6, 1, 4, 2, 5}, []int{0, 1, 4, 0, 2, 0, 3}},
+		{"separators", toByte("aba$aba", '$', 1), []int{3, 0, 4, 2, 6, 1, 5}, []int{0, 0, 1, 1, 3, 0, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sa, lcp := SuffixArrayLCP(tt.t)
+			if !reflect.DeepEqual(sa, tt.wantSA) {
+				t.Errorf("SuffixArrayLCP() sa = %v, want %v", sa, tt.wantSA)
+			}
+			if !reflect.DeepEqual(lcp, tt.wantLCP) {
+				t.Errorf("SuffixArrayLCP() lcp = %v, want %v", lcp, tt.wantLCP)
+			}
+		})
+	}
+
+	r := rand.New(rand.NewSource(5))
+	for i := 0; i < 2000; i++ {
+		b := randText(r, 2+r.Intn(60), 1+r.Intn(4), i%2 == 0)
+		sa, lcp := SuffixArrayLCP(b)
+		if want := naiveLCP(b, sa); !reflect.DeepEqual(lcp, want) {
+			t.Fatalf("SuffixArrayLCP(%q) = %v, want %v", toString(b, 1, '$'), lcp, want)
+		}
+
+		// same strings divided by '\n'
+		lines := toString(append([]byte{}, b...), 1, '\n')
+		if lsa, llcp := (&Options{Separator: '\n'}).SuffixArrayLCP([]byte(lines)); !reflect.DeepEqual(lsa, sa) ||
+			!reflect.DeepEqual(llcp, lcp) {
+			t.Fatalf("SuffixArrayLCP(%q) of '\\n' = %v, %v, want %v, %v", lines, lsa, llcp, sa, lcp)
+		}
+	}
+
+	// conventional suffix array, prefixes are read forward
+	std := &Options{Standard: true}
+	for i := 0; i < 500; i++ {
+		b := randText(r, 1+r.Intn(60), 1+r.Intn(3), i%2 == 0)
+		sa, lcp := std.SuffixArrayLCP(b)
+		for j := 1; j < len(sa); j++ {
+			h := 0
+			for x, y := sa[j-1], sa[j]; x+h < len(b) && y+h < len(b) && b[x+h] == b[y+h]; h++ {
+			}
+			if lcp[j] != h {
+				t.Fatalf("SuffixArrayLCP(%q) of Standard = %v, want %d at %d", b, lcp, h, j)
+			}
+		}
+	}
+}
@@ -0,0 +1,130 @@
+/*
+ * Copyright 2020 Rock Lei Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sa
+
+import "sort"
+
+// Match position of an occurrence in a string collection
+type Match struct {
+	// Doc index of the string divided by separators
+	Doc int
+
+	// Offset from the start of the string
+	Offset int
+}
+
+// sampledSA suffix array values of sampled rows of BWT
+type sampledSA struct {
+	rate int
+
+	// marks sampled rows
+	marks *bitvec
+
+	// vals suffix array values of sampled rows, in the order of rows
+	vals []int
+
+	// offsets start offset of each string
+	offsets []int
+}
+
+// Sample keeps every rate-th suffix array value for Locate, suffix array is sampled by walking the text
+// with LF mapping, it is not needed alongside BWT.
+// Note: the last position of every string is sampled as well, LF mapping does not apply to separators.
+func (f *FMIndex) Sample(rate int) {
+	if rate < 1 {
+		panic("sa: sample rate must be positive")
+	}
+
+	// rows and values of samples in the order of positions, p is the suffix array value of row i
+	s := &sampledSA{rate: rate, marks: newBitvec(f.rank.Len()), offsets: []int{0}}
+	var rows, vals []int
+	p := 0
+	f.walk(func(c byte, i int) {
+		if p%rate == 0 || f.stop(i) {
+			s.marks.set(i)
+			rows, vals = append(rows, i), append(vals, p)
+		}
+		if f.end < 0 && f.code[c] == separator {
+			s.offsets = append(s.offsets, p+1)
+		}
+		p++
+	})
+	s.marks.build()
+
+	// vals in the order of rows
+	s.vals = make([]int, len(rows))
+	for k, i := range rows {
+		s.vals[s.marks.rank1(i)] = vals[k]
+	}
+
+	f.ssa = s
+}
+
+// Locate returns the start positions of pattern in ascending order, Sample must be called before Locate
+func (f *FMIndex) Locate(pattern []byte) []int {
+	if f.ssa == nil {
+		panic("sa: Locate requires sampled suffix array")
+	}
+	if len(pattern) == 0 {
+		return nil
+	}
+
+	lo, hi := f.Interval(pattern)
+	pos := make([]int, 0, hi-lo)
+	for r := lo + 1; r <= hi; r++ {
+		pos = append(pos, f.locate(r)-len(pattern)+1)
+	}
+	sort.Ints(pos)
+
+	return pos
+}
+
+// LocateDocs returns the positions of pattern as string and offset in ascending order, see Locate
+func (f *FMIndex) LocateDocs(pattern []byte) []Match {
+	return toMatches(f.Locate(pattern), len(f.ssa.offsets), func(d int) int { return f.ssa.offsets[d] })
+}
+
+// toMatches converts ascending positions to string and offset, offset(d) is the start offset of string d of docs
+func toMatches(pos []int, docs int, offset func(d int) int) []Match {
+	matches := make([]Match, len(pos))
+	for i, p := range pos {
+		d := sort.Search(docs, func(d int) bool { return offset(d) > p }) - 1
+		matches[i] = Match{d, p - offset(d)}
+	}
+
+	return matches
+}
+
+// stop returns whether row r is the last position of a string, ie, its BWT is sentinel or separator
+func (f *FMIndex) stop(r int) bool {
+	if f.end >= 0 {
+		return r == f.end
+	}
+	return f.code[f.rank.Access(r)] <= separator
+}
+
+// locate returns suffix array value of row r, walks LF mapping to the next sampled row
+func (f *FMIndex) locate(r int) int {
+	steps := 0
+	for !f.ssa.marks.get(r) {
+		// LF mapping to the row of the next position
+		r = f.lf(f.rank.Access(r), r)
+		steps++
+	}
+
+	return f.ssa.vals[f.ssa.marks.rank1(r)] - steps
+}
@@ -0,0 +1,70 @@
+package sa
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestLocate(t *testing.T) {
+	r := rand.New(rand.NewSource(9))
+	for i := 0; i < 300; i++ {
+		text := randText(r, 2+r.Intn(600), 1+r.Intn(4), i%2 == 0)
+		sa := SuffixArray(text)
+		_, bwt, aux := BWT(append([]byte{}, text...))
+		f := NewFMIndex(bwt, aux)
+		f.Sample(1 + r.Intn(10))
+
+		// samples by LF mapping are the values of the suffix array
+		for j, p := range sa {
+			if f.ssa.marks.get(j+1) && f.ssa.vals[f.ssa.marks.rank1(j+1)] != p {
+				t.Fatalf("Sample() of row %d = %d, want %d", j+1, f.ssa.vals[f.ssa.marks.rank1(j+1)], p)
+			}
+		}
+
+		offsets := []int{0}
+		for j, c := range text {
+			if c == separator {
+				offsets = append(offsets, j+1)
+			}
+		}
+
+		for j := 0; j < 20; j++ {
+			p := randText(r, 1+r.Intn(4), 1+r.Intn(4), false)
+			want, wantDocs := []int{}, []Match{}
+			for _, e := range naiveEnds(text, p) {
+				s, d := e-len(p)+1, 0
+				for d+1 < len(offsets) && offsets[d+1] <= s {
+					d++
+				}
+				want = append(want, s)
+				wantDocs = append(wantDocs, Match{d, s - offsets[d]})
+			}
+
+			if got := f.Locate(p); !reflect.DeepEqual(got, want) {
+				t.Fatalf("Locate(%q) = %v, want %v", p, got, want)
+			}
+			if got := f.LocateDocs(p); !reflect.DeepEqual(got, wantDocs) {
+				t.Fatalf("LocateDocs(%q) = %v, want %v", p, got, wantDocs)
+			}
+		}
+	}
+}
+
+func TestLocateDocs(t *testing.T) {
+	var c Collection
+	for _, d := range []string{"sisisim", "sisisim", "anana"} {
+		c.Add([]byte(d))
+	}
+	tr, _ := c.Build()
+	f := NewFMIndex(tr.BWT, tr.Aux)
+	f.Sample(4)
+
+	want := []Match{{0, 4}, {1, 4}}
+	if got := f.LocateDocs([]byte("sim")); !reflect.DeepEqual(got, want) {
+		t.Errorf("LocateDocs() = %v, want %v", got, want)
+	}
+	if got := f.Locate([]byte("ana")); !reflect.DeepEqual(got, []int{16, 18}) {
+		t.Errorf("Locate() = %v, want %v", got, []int{16, 18})
+	}
+}
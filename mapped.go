@@ -0,0 +1,390 @@
+/*
+ * Copyright 2020 Rock Lei Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sa
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math/bits"
+	"sort"
+)
+
+const (
+	// mappedVersion version of the on disk layout of MappedIndex
+	mappedVersion = 3
+
+	// size of header, magic, version, 6 counters and dict
+	mappedHeader = 8 + 6*8 + alphabetSize
+)
+
+var mappedMagic = []byte("SAMX")
+
+// MappedIndex read-only FM-index queried in place over the layout written by FMIndex.WriteMapped,
+// eg, a memory mapped file opened by OpenMapped, nothing but dict and its cumulative counts is copied to heap.
+//
+// Layout, integers are little endian, every section starts at a multiple of 8 bytes:
+// magic "SAMX", uint32 version, uint64 number of rows, sample rate, number of samples,
+// number of documents, size of dict, row of the end of binary BWT or number of rows if not binary,
+// 256 bytes of dict, then sections of BWT bytes,
+// uint32 occurrence counters of every 256 rows for each byte of dict relative to their super block of 2^24
+// rows, uint64 counters of every super block for each byte of dict, same as occTable, uint64 words of sampled rows,
+// uint64 rank directory of sampled rows, uint64 sampled suffix array values and uint64 document offsets.
+// Note: there is no checksum, verifying it would read the whole file.
+type MappedIndex struct {
+	f *FMIndex
+
+	// marks sampled rows, ranks[b] number of sampled rows before block b of words
+	words, ranks []byte
+
+	// vals suffix array values of sampled rows
+	vals []byte
+
+	// offsets uint64 start offset of each string
+	offsets []byte
+
+	// rate sample rate, a sampled row is reached in less than rate steps of LF mapping
+	rate int
+
+	// closer releases the mapped data
+	closer func() error
+}
+
+// mappedOcc occurrence counters over mapped data, same as occTable
+type mappedOcc struct {
+	bwt  []byte
+	sz   int
+	code *[alphabetSize]int
+
+	// occ uint32 counters relative to super blocks, super uint64 counters, see occTable
+	occ, super []byte
+}
+
+// WriteMapped writes the layout of MappedIndex to w, Sample must be called before WriteMapped
+func (f *FMIndex) WriteMapped(w io.Writer) error {
+	if f.ssa == nil {
+		panic("sa: WriteMapped requires sampled suffix array")
+	}
+
+	n, sz, s := f.rank.Len(), len(f.dict), f.ssa
+	bw := bufio.NewWriter(w)
+	var b [8]byte
+	put := func(v uint64) {
+		binary.LittleEndian.PutUint64(b[:], v)
+		bw.Write(b[:])
+	}
+
+	bw.Write(mappedMagic)
+	binary.LittleEndian.PutUint32(b[:4], mappedVersion)
+	bw.Write(b[:4])
+	end := f.end
+	if end < 0 {
+		end = n
+	}
+	for _, v := range []int{n, s.rate, len(s.vals), len(s.offsets), sz, end} {
+		put(uint64(v))
+	}
+	var dict [alphabetSize]byte
+	copy(dict[:], f.dict)
+	bw.Write(dict[:])
+
+	// BWT and occurrence counters
+	row := make([]byte, n)
+	for i := range row {
+		row[i] = f.rank.Access(i)
+	}
+	bw.Write(row)
+	bw.Write(make([]byte, pad8(n)))
+
+	// the last block of counters is the total of every byte
+	blocks := (n + occBlock - 1) / occBlock
+	cnt, base, super := make([]uint64, sz), make([]uint64, sz), []uint64{}
+	for blk := 0; blk <= blocks; blk++ {
+		if blk%occSuper == 0 {
+			copy(base, cnt)
+			super = append(super, base...)
+		}
+		for k, v := range cnt {
+			binary.LittleEndian.PutUint32(b[:4], uint32(v-base[k]))
+			bw.Write(b[:4])
+		}
+		e := (blk + 1) * occBlock
+		if e > n {
+			e = n
+		}
+		for i := blk * occBlock; i < e; i++ {
+			cnt[f.code[row[i]]]++
+		}
+	}
+	bw.Write(make([]byte, pad8((blocks+1)*sz*4)))
+	for _, v := range super {
+		put(v)
+	}
+
+	// sampled suffix array
+	for _, x := range s.marks.bits {
+		put(x)
+	}
+	for _, r := range s.marks.ranks {
+		put(uint64(r))
+	}
+	for _, v := range s.vals {
+		put(uint64(v))
+	}
+	for _, o := range s.offsets {
+		put(uint64(o))
+	}
+
+	return bw.Flush()
+}
+
+// NewMappedIndex creates MappedIndex over data written by WriteMapped, data is referenced, not copied
+func NewMappedIndex(data []byte) (*MappedIndex, error) {
+	if len(data) < mappedHeader || !bytes.Equal(data[:4], mappedMagic) {
+		return nil, ErrFormat
+	} else if binary.LittleEndian.Uint32(data[4:]) != mappedVersion {
+		return nil, ErrVersion
+	}
+
+	var hdr [6]int
+	for i := range hdr {
+		v := binary.LittleEndian.Uint64(data[8+8*i:])
+		if v > uint64(len(data)) {
+			return nil, ErrFormat
+		}
+		hdr[i] = int(v)
+	}
+	n, samples, docs, sz, end := hdr[0], hdr[2], hdr[3], hdr[4], hdr[5]
+	// note: every text but the empty one has samples, row 0 of sentinel is never sampled
+	if n < 1 || hdr[1] < 1 || (samples < 1) != (n == 1) || samples >= n || docs < 1 || docs > n ||
+		sz < 2 || sz > alphabetSize {
+		return nil, ErrFormat
+	} else if end == n {
+		end = -1
+	} else if end > n {
+		return nil, ErrFormat
+	}
+
+	// sections
+	words, blocks := (n+63)>>6, (n+occBlock-1)/occBlock
+	occ := (blocks + 1) * sz * 4
+	sizes := []int{n + pad8(n), occ + pad8(occ), (blocks/occSuper + 1) * sz * 8, words * 8, (words/rankBlock + 1) * 8,
+		samples * 8, docs * 8}
+	sections, off := make([][]byte, len(sizes)), mappedHeader
+	for i, size := range sizes {
+		if off+size > len(data) {
+			return nil, ErrFormat
+		}
+		sections[i], off = data[off:off+size], off+size
+	}
+
+	// note: dict[2:] ascends, sentinel and separator can be any byte, see Options.Separator,
+	// symbols of binary BWT start over from dict[2], see Aux.Binary
+	dict := append([]byte{}, data[8+6*8:8+6*8+sz]...)
+	var seen [alphabetSize]bool
+	for k, c := range dict {
+		if k > separator+1 && dict[k-1] >= c || end < 0 && seen[c] {
+			return nil, ErrFormat
+		}
+		seen[c] = true
+	}
+	if end >= 0 && sections[0][end] != 0 || !validMarks(sections[3], sections[4], n, samples) {
+		return nil, ErrFormat
+	}
+	f := &FMIndex{dict: dict, end: end}
+	for i := range f.code {
+		f.code[i] = -1
+	}
+	for k, c := range dict {
+		f.code[c] = k
+	}
+
+	// the last block of counters is the total of every byte, the end of binary BWT is sentinel
+	o := &mappedOcc{sections[0][:n], sz, &f.code, sections[1], sections[2]}
+	f.c = make([]int, sz)
+	sum := uint64(0)
+	for k := 0; k < sz; k++ {
+		f.c[k] = int(sum)
+		cnt := uint64(o.count(blocks, k))
+		if end >= 0 {
+			switch {
+			case k == 0:
+				cnt = 1
+			case k == separator:
+				cnt = 0
+			case dict[k] == 0 && cnt > 0:
+				cnt--
+			}
+		}
+		if sum += cnt; sum > uint64(n) {
+			return nil, ErrFormat
+		}
+	}
+	if sum != uint64(n) {
+		return nil, ErrFormat
+	}
+	f.rank = o
+
+	// note: offsets are read in place, only the first and the last are checked against the text
+	m := &MappedIndex{f: f, words: sections[3], ranks: sections[4], vals: sections[5], offsets: sections[6], rate: hdr[1]}
+	if m.offset(0) != 0 || binary.LittleEndian.Uint64(m.offsets[8*(docs-1):]) >= uint64(n) {
+		return nil, ErrFormat
+	}
+
+	return m, nil
+}
+
+// validMarks checks that words of n bits have samples ones, none past n, and the rank directory counts them
+func validMarks(words, ranks []byte, n, samples int) bool {
+	sum := 0
+	for w := 0; w < len(words)/8; w++ {
+		if w%rankBlock == 0 && binary.LittleEndian.Uint64(ranks[8*(w/rankBlock):]) != uint64(sum) {
+			return false
+		}
+		x := binary.LittleEndian.Uint64(words[8*w:])
+		if r := n - w<<6; r < 64 && x>>uint(r) != 0 {
+			return false
+		}
+		sum += bits.OnesCount64(x)
+	}
+	if w := len(words) / 8; w%rankBlock == 0 && binary.LittleEndian.Uint64(ranks[8*(w/rankBlock):]) != uint64(sum) {
+		return false
+	}
+	return sum == samples
+}
+
+// Close releases the mapped file, the index must not be used after Close
+func (m *MappedIndex) Close() error {
+	if m.closer == nil {
+		return nil
+	}
+	err := m.closer()
+	m.closer = nil
+	return err
+}
+
+// Len returns the length of the text
+func (m *MappedIndex) Len() int {
+	return m.f.Len()
+}
+
+// Count returns the number of occurrences of pattern
+func (m *MappedIndex) Count(pattern []byte) int {
+	return m.f.Count(pattern)
+}
+
+// Locate returns the start positions of pattern in ascending order, see FMIndex.Locate,
+// ErrFormat if LF mapping over the mapped data does not reach a sampled row, ie, the data is corrupted
+func (m *MappedIndex) Locate(pattern []byte) ([]int, error) {
+	if len(pattern) == 0 {
+		return nil, nil
+	}
+
+	lo, hi := m.f.Interval(pattern)
+	pos := make([]int, 0, hi-lo)
+	for r := lo + 1; r <= hi; r++ {
+		p, err := m.locate(r)
+		if err != nil {
+			return nil, err
+		}
+		pos = append(pos, p-len(pattern)+1)
+	}
+	sort.Ints(pos)
+
+	return pos, nil
+}
+
+// LocateDocs returns the positions of pattern as string and offset in ascending order, see Locate
+func (m *MappedIndex) LocateDocs(pattern []byte) ([]Match, error) {
+	pos, err := m.Locate(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	return toMatches(pos, len(m.offsets)/8, m.offset), nil
+}
+
+// offset returns the start offset of string d
+func (m *MappedIndex) offset(d int) int {
+	return int(binary.LittleEndian.Uint64(m.offsets[8*d:]))
+}
+
+// locate returns suffix array value of row r, see FMIndex.locate,
+// ErrFormat on a BWT byte not in dict or more than rate steps, which validation at open does not catch
+func (m *MappedIndex) locate(r int) (int, error) {
+	steps := 0
+	for !m.marked(r) {
+		c := m.f.rank.Access(r)
+		if m.f.code[c] < 0 || steps == m.rate {
+			return 0, ErrFormat
+		}
+		if r = m.f.lf(c, r); r < 0 || r >= m.f.rank.Len() {
+			return 0, ErrFormat
+		}
+		steps++
+	}
+
+	return int(binary.LittleEndian.Uint64(m.vals[8*m.rank1(r):])) - steps, nil
+}
+
+// marked returns whether row r is sampled
+func (m *MappedIndex) marked(r int) bool {
+	return binary.LittleEndian.Uint64(m.words[8*(r>>6):])&(1<<uint(r&63)) != 0
+}
+
+// rank1 returns number of sampled rows before row r, see bitvec.rank1
+func (m *MappedIndex) rank1(r int) int {
+	w := r >> 6
+	n := int(binary.LittleEndian.Uint64(m.ranks[8*(w/rankBlock):]))
+	for x := w / rankBlock * rankBlock; x < w; x++ {
+		n += bits.OnesCount64(binary.LittleEndian.Uint64(m.words[8*x:]))
+	}
+	if r&63 > 0 {
+		n += bits.OnesCount64(binary.LittleEndian.Uint64(m.words[8*w:]) & (1<<uint(r&63) - 1))
+	}
+	return n
+}
+
+func (o *mappedOcc) Len() int {
+	return len(o.bwt)
+}
+
+func (o *mappedOcc) Access(i int) byte {
+	return o.bwt[i]
+}
+
+func (o *mappedOcc) Rank(c byte, i int) int {
+	k := o.code[c]
+	if k < 0 {
+		return 0
+	}
+
+	b := i / occBlock
+	return o.count(b, k) + bytes.Count(o.bwt[b*occBlock:i], []byte{c})
+}
+
+// count returns the number of dict[k] before block b, see occTable.count
+func (o *mappedOcc) count(b, k int) int {
+	s := binary.LittleEndian.Uint64(o.super[8*(b/occSuper*o.sz+k):])
+	return int(s) + int(binary.LittleEndian.Uint32(o.occ[4*(b*o.sz+k):]))
+}
+
+// pad8 returns padding of n bytes to a multiple of 8
+func pad8(n int) int {
+	return (8 - n%8) % 8
+}
@@ -0,0 +1,184 @@
+package sa
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMappedIndex(t *testing.T) {
+	r := rand.New(rand.NewSource(15))
+	for i := 0; i < 100; i++ {
+		text := randText(r, 2+r.Intn(3000), 1+r.Intn(8), i%2 == 0)
+		_, bwt, aux := BWT(append([]byte{}, text...))
+		f := NewFMIndex(bwt, aux)
+		f.Sample(1 + r.Intn(10))
+
+		var buf bytes.Buffer
+		if err := f.WriteMapped(&buf); err != nil {
+			t.Fatal(err)
+		}
+		m, err := NewMappedIndex(buf.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if m.Len() != f.Len() {
+			t.Fatalf("Len() = %d, want %d", m.Len(), f.Len())
+		}
+		for j := 0; j < 20; j++ {
+			p := randText(r, 1+r.Intn(4), 1+r.Intn(8), false)
+			if got, want := m.Count(p), f.Count(p); got != want {
+				t.Fatalf("Count(%q) = %d, want %d", p, got, want)
+			}
+			if got, err := m.Locate(p); err != nil || !reflect.DeepEqual(got, f.Locate(p)) {
+				t.Fatalf("Locate(%q) = %v, %v, want %v", p, got, err, f.Locate(p))
+			}
+			if got, err := m.LocateDocs(p); err != nil || !reflect.DeepEqual(got, f.LocateDocs(p)) {
+				t.Fatalf("LocateDocs(%q) = %v, %v, want %v", p, got, err, f.LocateDocs(p))
+			}
+		}
+
+		if _, err := NewMappedIndex(buf.Bytes()[:buf.Len()-8]); err != ErrFormat {
+			t.Fatalf("NewMappedIndex() of truncated data = %v, want %v", err, ErrFormat)
+		}
+
+		// sample count, a sampled row and a document offset do not match the rest of the layout
+		n := f.rank.Len()
+		occ, supers := ((n+occBlock-1)/occBlock+1)*len(f.dict)*4, ((n+occBlock-1)/occBlock/occSuper+1)*len(f.dict)*8
+		words := mappedHeader + n + pad8(n) + occ + pad8(occ) + supers
+		for _, off := range []int{8 + 2*8, words + 8*r.Intn((n+63)>>6), buf.Len() - 8*len(f.ssa.offsets)} {
+			bad := append([]byte{}, buf.Bytes()...)
+			bad[off] ^= 0x40
+			if _, err := NewMappedIndex(bad); err != ErrFormat {
+				t.Fatalf("NewMappedIndex() of corrupted byte %d = %v, want %v", off, err, ErrFormat)
+			}
+		}
+
+		// a BWT byte not in dict passes validation, locating its row reports it instead of panic
+		for u := 1; u < n; u++ {
+			if f.ssa.marks.get(u) {
+				continue
+			}
+			c := 0
+			for f.code[c] >= 0 {
+				c++
+			}
+			bad := append([]byte{}, buf.Bytes()...)
+			bad[mappedHeader+u] = byte(c)
+			m, err := NewMappedIndex(bad)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := m.locate(u); err != ErrFormat {
+				t.Fatalf("locate(%d) of corrupted BWT = %v, want %v", u, err, ErrFormat)
+			}
+			break
+		}
+	}
+
+	// empty text has no samples
+	_, bwt, aux := BWT(nil)
+	f := NewFMIndex(bwt, aux)
+	f.Sample(4)
+	var buf bytes.Buffer
+	if err := f.WriteMapped(&buf); err != nil {
+		t.Fatal(err)
+	}
+	m, err := NewMappedIndex(buf.Bytes())
+	if err != nil {
+		t.Fatalf("NewMappedIndex() of empty text = %v", err)
+	}
+	if pos, err := m.Locate([]byte("a")); m.Len() != 0 || m.Count([]byte("a")) != 0 || len(pos) != 0 || err != nil {
+		t.Errorf("MappedIndex of empty text = %d, %v, %v", m.Len(), pos, err)
+	}
+}
+
+func TestOpenMapped(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var c Collection
+	for _, d := range []string{"sisisim", "sisisim", "anana"} {
+		c.Add([]byte(d))
+	}
+	tr, _ := c.Build()
+	f := NewFMIndex(tr.BWT, tr.Aux)
+	f.Sample(4)
+
+	path := filepath.Join(dir, "index")
+	var buf bytes.Buffer
+	f.WriteMapped(&buf)
+	if err := ioutil.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := OpenMapped(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Match{{0, 4}, {1, 4}}
+	if got, err := m.LocateDocs([]byte("sim")); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("LocateDocs() = %v, %v, want %v", got, err, want)
+	}
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(path, []byte("not an index"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenMapped(path); err != ErrFormat {
+		t.Fatalf("OpenMapped() = %v, want %v", err, ErrFormat)
+	}
+}
+
+func TestMappedOccSuper(t *testing.T) {
+	// counters of more than one super block, same as occTable
+	r := rand.New(rand.NewSource(28))
+	bwt := make([]byte, occSuper*occBlock+1000)
+	dict := []byte{0, 1, 'a', 'c', 'g', 't'}
+	for i := range bwt {
+		bwt[i] = dict[2+r.Intn(4)]
+	}
+	bwt[0] = 0
+
+	f := &FMIndex{dict: dict, end: -1}
+	for i := range f.code {
+		f.code[i] = -1
+	}
+	for k, c := range dict {
+		f.code[c] = k
+	}
+	f.rank = newOccTable(bwt, dict, &f.code)
+	f.ssa = &sampledSA{rate: 1, marks: newBitvec(len(bwt)), vals: []int{0}, offsets: []int{0}}
+	f.ssa.marks.set(1)
+	f.ssa.marks.build()
+
+	var buf bytes.Buffer
+	if err := f.WriteMapped(&buf); err != nil {
+		t.Fatal(err)
+	}
+	m, err := NewMappedIndex(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	positions := []int{0, len(bwt), occSuper * occBlock, occSuper*occBlock - 1, occSuper*occBlock + 200}
+	for i := 0; i < 30; i++ {
+		positions = append(positions, r.Intn(len(bwt)+1))
+	}
+	for _, i := range positions {
+		c := dict[2+r.Intn(4)]
+		if got, want := m.f.rank.Rank(c, i), f.rank.Rank(c, i); got != want {
+			t.Fatalf("Rank(%c, %d) = %d, want %d", c, i, got, want)
+		}
+	}
+}
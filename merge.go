@@ -0,0 +1,183 @@
+/*
+ * Copyright 2020 Rock Lei Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sa
+
+// MergeBWT merges BWT a and b into the BWT of a and b divided by separator, ie, the strings of b are
+// appended to the strings of a. auxA and auxB are the Aux returned with a and b. Note: the end of
+// text of a becomes separator, the end of text of b is the only byte value (0) in the merged BWT.
+// Binary BWT has no separator, MergeBWT panics if auxA or auxB is Binary. a and b are translated by
+// Separator and Sentinel of Aux, MergeBWT panics if they are different.
+//
+// The rows of a and b are interleaved by iterating LF mapping until the interleave converges
+// (Holt and McMillan), the rows of a sort before the rows of b if they are equal up to separators.
+// Every pass is linear, and the number of passes is the longest common prefix of a row of a and a row of
+// b up to separators, ie, O(n × the longest document) in the worst case, merging near duplicate documents
+// is slow.
+func MergeBWT(a, b []byte, auxA, auxB *Aux) ([]byte, *Aux) {
+	if auxA.Len != uint(len(a)) || auxB.Len != uint(len(b)) {
+		panic("sa: Aux does not match BWT")
+	}
+	if auxA.Binary || auxB.Binary {
+		panic("sa: binary BWT cannot be merged")
+	}
+	if auxA.Separator != auxB.Separator || auxA.Sentinel != auxB.Sentinel {
+		panic("sa: BWTs of different separator or sentinel cannot be merged")
+	}
+	if code := auxA.code(); code != nil {
+		// note: Aux is of the translated bytes, merge translated copies
+		a, b = append([]byte(nil), a...), append([]byte(nil), b...)
+		translate(a, code)
+		translate(b, code)
+		bwt, aux := mergeBWT(a, b, auxA, auxB)
+		translate(bwt, decode(code))
+		aux.Separator, aux.Sentinel = auxA.Separator, auxA.Sentinel
+		return bwt, aux
+	}
+
+	return mergeBWT(a, b, auxA, auxB)
+}
+
+// mergeBWT merges a and b of the bytes of Aux, see MergeBWT
+func mergeBWT(a, b []byte, auxA, auxB *Aux) ([]byte, *Aux) {
+	ha, hb := auxA.hist(), auxB.hist()
+
+	// bkt[c] -> start of bucket c in the merged BWT, sentinel, end of text and separators of both
+	// a and b are at the start of merged BWT, they are sorted by their positions
+	hist, bkt := make([]int, alphabetSize), make([]int, alphabetSize)
+	for c := range hist {
+		hist[c] = ha[c] + hb[c]
+	}
+	hist[separator] += hist[0]
+	hist[0] = 0
+	setBktBeg(bkt, hist)
+
+	// interleave, false -> row of a, true -> row of b
+	// sorted by the first byte, rows of a are before rows of b in the same bucket
+	z := make([]bool, len(a)+len(b))
+	for c := separator; c < alphabetSize; c++ {
+		n := ha[c]
+		if c == separator {
+			// sentinel of a is counted as end of text
+			n += ha[0]
+		}
+		for i := bkt[c] + n; i < bkt[c]+hist[c]; i++ {
+			z[i] = true
+		}
+	}
+
+	ptr, next := make([]int, alphabetSize), make([]bool, len(z))
+	for changed := true; changed; {
+		// sentinel and separators are sorted, copy them over
+		copy(next[:bkt[separator+1]], z)
+		copy(ptr, bkt)
+
+		// the row of LF mapping goes to the next free slot of its bucket in the order of z
+		ia, ib := 0, 0
+		for _, fromB := range z {
+			var c byte
+			if fromB {
+				c, ib = b[ib], ib+1
+			} else {
+				c, ia = a[ia], ia+1
+			}
+			if c > separator {
+				next[ptr[c]] = fromB
+				ptr[c]++
+			}
+		}
+
+		changed = false
+		for i := range z {
+			if z[i] != next[i] {
+				changed = true
+				break
+			}
+		}
+		z, next = next, z
+	}
+
+	bwt := make([]byte, len(z))
+	ia, ib := 0, 0
+	for i, fromB := range z {
+		if fromB {
+			bwt[i], ib = b[ib], ib+1
+		} else {
+			if bwt[i], ia = a[ia], ia+1; bwt[i] == 0 {
+				// end of text of a is followed by separator
+				bwt[i] = separator
+			}
+		}
+	}
+
+	return bwt, newAux(bwt, rankBWT(bwt), dictBWT(bwt))
+}
+
+// hist returns the number of occurrences of each byte in BWT, counted from Eob, Dist and Hist
+func (x *Aux) hist() []int {
+	h := make([]int, alphabetSize)
+	for c, eob := range x.Eob {
+		for _, d := range eob {
+			if d > 0 {
+				for _, v := range x.Hist[x.Dist[d-1]:x.Dist[d]] {
+					h[c] += int(v >> 8)
+				}
+			}
+		}
+	}
+
+	// note: end of text is counted as separator in Eob, unless Binary
+	if !x.Binary {
+		h[separator]--
+		h[0]++
+	}
+
+	return h
+}
+
+// rankBWT counts rows of bwt by byte of the row and byte of its bucket, same as induceBWT
+func rankBWT(bwt []byte) []uint {
+	arr := make([]uint, 256*256, 256*256)
+	rnk := make2Darr(arr, 256)
+	hist, _ := histgram(bytebuf(bwt), alphabetSize)
+
+	// sentinel is at row 0, followed by separators, end of text is counted as separator
+	updateRank(rnk, int(bwt[0]), 1)
+	i := 1
+	for ; i < hist[0]+hist[separator]; i++ {
+		updateRank(rnk, int(bwt[i]), 0)
+	}
+	for c := separator + 1; c < alphabetSize; c++ {
+		for e := i + hist[c]; i < e; i++ {
+			updateRank(rnk, int(bwt[i]), c)
+		}
+	}
+
+	return arr
+}
+
+// dictBWT returns ascending bytes of bwt, same as makeCounters
+func dictBWT(bwt []byte) []byte {
+	hist, _ := histgram(bytebuf(bwt), alphabetSize)
+	dict := []byte{0}
+	for c := separator + 1; c < alphabetSize; c++ {
+		if hist[c] > 0 {
+			dict = append(dict, byte(c))
+		}
+	}
+
+	return dict
+}
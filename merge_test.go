@@ -0,0 +1,112 @@
+package sa
+
+import (
+	"bytes"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestMergeBWT(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+	}{
+		{"same", "sisisim", "sisisim"},
+		{"prefix", "anana", "ananab"},
+		{"collections", "nana$abana", "ananab$ananab"},
+		{"single", "a", "b"},
+	}
+	r := rand.New(rand.NewSource(3))
+	for i := 0; i < 300; i++ {
+		k := 1 + r.Intn(4)
+		a, b := randText(r, 2+r.Intn(40), k, i%2 == 0), randText(r, 2+r.Intn(40), k, i%3 == 0)
+		tests = append(tests, struct {
+			name string
+			a, b string
+		}{"random", toString(a, 1, '$'), toString(b, 1, '$')})
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, a, auxA := BWT(toByte(tt.a, '$', 1))
+			_, b, auxB := BWT(toByte(tt.b, '$', 1))
+			l, want, wantAux := BWT(toByte(tt.a+"$"+tt.b, '$', 1))
+
+			got, gotAux := MergeBWT(a, b, auxA, auxB)
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("MergeBWT(%q, %q) = %v, want %v", tt.a, tt.b, got, want)
+			}
+			if !reflect.DeepEqual(gotAux, wantAux) {
+				t.Errorf("MergeBWT(%q, %q) aux = %v, want %v", tt.a, tt.b, gotAux, wantAux)
+			}
+			if s := toString(InverseBWT(got, l), 1, '$'); s != tt.a+"$"+tt.b {
+				t.Errorf("InverseBWT() = %q, want %q", s, tt.a+"$"+tt.b)
+			}
+		})
+	}
+}
+
+func TestAux(t *testing.T) {
+	r := rand.New(rand.NewSource(4))
+	for i := 0; i < 200; i++ {
+		a := randText(r, 2+r.Intn(60), 1+r.Intn(4), i%2 == 0)
+		_, b, aux := BWT(a)
+		if got := newAux(b, rankBWT(b), dictBWT(b)); !reflect.DeepEqual(got, aux) {
+			t.Fatalf("newAux(%v) = %v, want %v", b, got, aux)
+		}
+
+		hist, _ := histgram(bytebuf(b), alphabetSize)
+		if got := aux.hist(); !reflect.DeepEqual(got, hist) {
+			t.Fatalf("hist() = %v, want %v", got, hist)
+		}
+	}
+}
+
+func TestPackHist(t *testing.T) {
+	hist := packHist(nil, 10, 'a', 3)
+	want := []uint{3<<8 | 'a', 3<<8 | 'a', 3<<8 | 'a', 1<<8 | 'a'}
+	if !reflect.DeepEqual(hist, want) {
+		t.Errorf("packHist() = %v, want %v", hist, want)
+	}
+	if hist = packHist(nil, 3, 'a', 3); !reflect.DeepEqual(hist, want[:1]) {
+		t.Errorf("packHist() = %v, want %v", hist, want[:1])
+	}
+}
+
+// splitHist splits Hist entries of aux into counts of at most max, as packHist does for large counts
+func splitHist(aux *Aux, max uint) *Aux {
+	x := &Aux{aux.Len, aux.Eob, []uint{0}, nil, aux.Dict, aux.Binary, aux.End, aux.Separator, aux.Sentinel}
+	for d := 1; d < len(aux.Dist); d++ {
+		for _, v := range aux.Hist[aux.Dist[d-1]:aux.Dist[d]] {
+			x.Hist = packHist(x.Hist, v>>8, v&0xff, max)
+		}
+		x.Dist = append(x.Dist, uint(len(x.Hist)))
+	}
+	return x
+}
+
+func TestSplitHist(t *testing.T) {
+	// counts larger than 2^24 are split on 32 bit platforms, same counts with entries of 1
+	r := rand.New(rand.NewSource(19))
+	for i := 0; i < 100; i++ {
+		a, b := randText(r, 50+r.Intn(300), 1+r.Intn(3), true), randText(r, 50+r.Intn(300), 1+r.Intn(3), true)
+		_, x, auxA := BWT(a)
+		_, y, auxB := BWT(b)
+		splitA, splitB := splitHist(auxA, 1), splitHist(auxB, 1)
+		if len(splitA.Hist) <= len(auxA.Hist) {
+			t.Fatalf("splitHist() does not split %v", auxA.Hist)
+		}
+		if got, want := splitA.hist(), auxA.hist(); !reflect.DeepEqual(got, want) {
+			t.Fatalf("hist() of split entries = %v, want %v", got, want)
+		}
+
+		want, _ := MergeBWT(x, y, auxA, auxB)
+		if got, _ := MergeBWT(x, y, splitA, splitB); !bytes.Equal(got, want) {
+			t.Fatalf("MergeBWT() of split entries = %v, want %v", got, want)
+		}
+		if got, want := NewFMIndex(x, splitA).Count(a[:2]), NewFMIndex(x, auxA).Count(a[:2]); got != want {
+			t.Fatalf("Count() of split entries = %d, want %d", got, want)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+//go:build linux
+// +build linux
+
+/*
+ * Copyright 2020 Rock Lei Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sa
+
+import (
+	"os"
+	"syscall"
+)
+
+// OpenMapped maps file written by FMIndex.WriteMapped read-only, pages are loaded on demand,
+// Close unmaps the file
+func OpenMapped(path string) (*MappedIndex, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	} else if fi.Size() < mappedHeader {
+		return nil, ErrFormat
+	}
+
+	data, err := syscall.Mmap(int(f.Fd()), 0, int(fi.Size()), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := NewMappedIndex(data)
+	if err != nil {
+		syscall.Munmap(data)
+		return nil, err
+	}
+	m.closer = func() error { return syscall.Munmap(data) }
+
+	return m, nil
+}
@@ -0,0 +1,166 @@
+/*
+ * Copyright 2020 Rock Lei Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sa
+
+import "sync"
+
+const (
+	// number of SA entries per worker of a block of induced sorting
+	parBlock = 1 << 14
+
+	// minimum length of text to sort in parallel, goroutines do not pay off below
+	parMinLen = 1 << 20
+)
+
+// parallel calls f on workers ranges of [0, n) concurrently
+func parallel(workers, n int, f func(lo, hi int)) {
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		lo, hi := n*w/workers, n*(w+1)/workers
+		if lo < hi {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				f(lo, hi)
+			}()
+		}
+	}
+	wg.Wait()
+}
+
+// blockScan scans SA in blocks for block based parallel induced sorting, in the style of libsais.
+//
+// A scan of SA spends most of its time on random reads of the text at sa[i] and random writes of
+// induced suffixes. For every block of SA, workers gather the entries of the block and their text
+// concurrently, the scan induces the block sequentially into the cache, suffixes induced into the block
+// are processed later in the same block, then workers write the block and the suffixes induced out of
+// the block back to SA concurrently. Suffixes are induced in the same order as a sequential scan, so
+// the result is identical.
+type blockScan struct {
+	t       buf
+	workers int
+
+	// read returns sa[i], write sets sa[i] to v
+	read  func(i int) int
+	write func(i, v int)
+
+	// lo, hi current block of SA, vals[j] is sa[lo+j], keys[j] is the text position of syms[j] and
+	// nexts[j], ie, text at the position and the position + 1, -1 if text is not gathered
+	lo, hi                  int
+	vals, keys, syms, nexts []int
+
+	// sa[dest[k]] is out[k], suffixes induced out of the block
+	dest, out []int
+}
+
+func newBlockScan(t buf, workers int, read func(i int) int, write func(i, v int)) *blockScan {
+	sz := workers * parBlock
+	return &blockScan{t: t, workers: workers, read: read, write: write,
+		vals: make([]int, sz), keys: make([]int, sz), syms: make([]int, sz), nexts: make([]int, sz)}
+}
+
+// scan calls step on every entry of sa[:n], from left to right if asc, text is gathered at position
+// sa[i] + shift, step must read and write sa by get, sym and set
+func (c *blockScan) scan(n, shift int, asc bool, step func(i int)) {
+	sz := len(c.vals)
+	if asc {
+		for lo := 0; lo < n; lo += sz {
+			hi := lo + sz
+			if hi > n {
+				hi = n
+			}
+			c.gather(lo, hi, shift)
+			for i := lo; i < hi; i++ {
+				step(i)
+			}
+			c.place()
+		}
+		return
+	}
+
+	for hi := n; hi > 0; hi -= sz {
+		lo := hi - sz
+		if lo < 0 {
+			lo = 0
+		}
+		c.gather(lo, hi, shift)
+		for i := hi - 1; i >= lo; i-- {
+			step(i)
+		}
+		c.place()
+	}
+}
+
+// gather reads sa[lo:hi] and text of the entries concurrently
+func (c *blockScan) gather(lo, hi, shift int) {
+	c.lo, c.hi = lo, hi
+	end := c.t.len()
+	parallel(c.workers, hi-lo, func(x, y int) {
+		for j := x; j < y; j++ {
+			v := c.read(lo + j)
+			c.vals[j], c.keys[j] = v, -1
+			if p := v + shift; v >= 0 && p < end {
+				c.keys[j], c.syms[j], c.nexts[j] = p, c.t.get(p), next(c.t, p)
+			}
+		}
+	})
+}
+
+// place writes the block and suffixes induced out of the block to sa concurrently
+func (c *blockScan) place() {
+	lo, n := c.lo, c.hi-c.lo
+	parallel(c.workers, n+len(c.dest), func(x, y int) {
+		for k := x; k < y; k++ {
+			if k < n {
+				c.write(lo+k, c.vals[k])
+			} else {
+				c.write(c.dest[k-n], c.out[k-n])
+			}
+		}
+	})
+	c.dest, c.out = c.dest[:0], c.out[:0]
+}
+
+// get returns sa[i] of the current block
+func (c *blockScan) get(i int) int {
+	return c.vals[i-c.lo]
+}
+
+// sym returns text at position p of entry sa[i] and text at p + 1, -1 if p + 1 is beyond the text
+func (c *blockScan) sym(i, p int) (int, int) {
+	if j := i - c.lo; c.keys[j] == p {
+		return c.syms[j], c.nexts[j]
+	}
+	return c.t.get(p), next(c.t, p)
+}
+
+// set sets sa[i] to v, text of an entry of the block is read again by sym after set
+func (c *blockScan) set(i, v int) {
+	if i >= c.lo && i < c.hi {
+		c.vals[i-c.lo], c.keys[i-c.lo] = v, -1
+	} else {
+		c.dest, c.out = append(c.dest, i), append(c.out, v)
+	}
+}
+
+// next returns text at position p + 1, -1 if it is beyond the text
+func next(t buf, p int) int {
+	if p+1 < t.len() {
+		return t.get(p + 1)
+	}
+	return -1
+}
@@ -0,0 +1,279 @@
+/*
+ * Copyright 2020 Rock Lei Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sa
+
+import "sort"
+
+// RLBWT run-length encoded BWT, rank in space proportional to the number of runs
+type RLBWT struct {
+	n int
+
+	// starts[j] start row of run j, heads[j] byte of run j
+	starts []int
+	heads  []byte
+
+	// runs[c] indexes of runs of byte c, cum[c][m] number of byte c before run runs[c][m]
+	runs [alphabetSize][]int
+	cum  [alphabetSize][]int
+}
+
+// NewRLBWT creates RLBWT of bwt returned by BWT.
+// Note: every end of text and separator is a run by itself, LF mapping does not apply to separators.
+func NewRLBWT(bwt []byte) *RLBWT {
+	r := &RLBWT{n: len(bwt)}
+	for i, c := range bwt {
+		if i == 0 || c != bwt[i-1] || c <= separator {
+			r.starts, r.heads = append(r.starts, i), append(r.heads, c)
+			if r.cum[c] == nil {
+				r.cum[c] = []int{0}
+			}
+			r.runs[c] = append(r.runs[c], len(r.starts)-1)
+			r.cum[c] = append(r.cum[c], r.cum[c][len(r.cum[c])-1])
+		}
+		r.cum[c][len(r.cum[c])-1]++
+	}
+
+	return r
+}
+
+// Runs returns the number of runs
+func (r *RLBWT) Runs() int {
+	return len(r.starts)
+}
+
+// Len returns the number of rows
+func (r *RLBWT) Len() int {
+	return r.n
+}
+
+// Access returns the byte of row i
+func (r *RLBWT) Access(i int) byte {
+	return r.heads[r.run(i)]
+}
+
+// Rank returns the number of byte c before row i
+func (r *RLBWT) Rank(c byte, i int) int {
+	if i >= r.n {
+		if len(r.cum[c]) == 0 {
+			return 0
+		}
+		return r.cum[c][len(r.cum[c])-1]
+	}
+
+	j := r.run(i)
+	m := sort.SearchInts(r.runs[c], j)
+	if len(r.cum[c]) == 0 {
+		return 0
+	} else if r.heads[j] == c {
+		return r.cum[c][m] + i - r.starts[j]
+	}
+	return r.cum[c][m]
+}
+
+// run returns the index of the run of row i
+func (r *RLBWT) run(i int) int {
+	return sort.Search(len(r.starts), func(j int) bool { return r.starts[j] > i }) - 1
+}
+
+// end returns the last row of run j
+func (r *RLBWT) end(j int) int {
+	if j+1 < len(r.starts) {
+		return r.starts[j+1] - 1
+	}
+	return r.n - 1
+}
+
+// RIndex count and locate over RLBWT, suffix array is sampled at the start and end of every run
+type RIndex struct {
+	f  *FMIndex
+	rl *RLBWT
+
+	// ends[j] suffix array value of the last row of run j
+	ends []int
+
+	// phi returns suffix array value of the previous row, keys are suffix array value + 1 of the
+	// first row of every run, sorted, phi(x) = vals[k] + x - keys[k] where keys[k] is the largest key <= x
+	keys, vals []int
+
+	// offsets start offset of each string
+	offsets []int
+
+	// code translates patterns to the bytes of Aux, nil if bytes are not translated, see Aux.Separator
+	code *[alphabetSize]byte
+}
+
+// NewRIndex creates RIndex of bwt, bwt and aux are returned by BWT. bwt is not referenced after
+// NewRIndex returns, suffix array is sampled by walking the text with LF mapping.
+// Binary BWT is not supported, NewRIndex panics if aux is Binary, use FMIndex instead.
+func NewRIndex(bwt []byte, aux *Aux) *RIndex {
+	if aux.Binary {
+		panic("sa: RIndex of binary BWT is not supported")
+	}
+
+	// note: runs are of the bytes of Aux, patterns are translated by the same code
+	code := aux.code()
+	if code != nil {
+		bwt = append([]byte(nil), bwt...)
+		translate(bwt, code)
+		x := *aux
+		x.Separator, x.Sentinel = 0, 0
+		aux = &x
+	}
+
+	rl := NewRLBWT(bwt)
+	x := &RIndex{f: NewFMIndexRanker(rl, aux), rl: rl, ends: make([]int, rl.Runs()), offsets: []int{0}, code: code}
+	f := x.f
+
+	// rows before the start of buckets, whose suffix array value is needed by the first run of the bucket
+	bucket := map[int]int{}
+	for _, c := range f.dict[separator+1:] {
+		bucket[f.c[f.code[c]]-1] = -1
+	}
+
+	// walk the text from sentinel, p is the suffix array value of row i
+	starts, sep := make([]int, rl.Runs()), f.c[f.code[separator]]
+	for i, p, j := 0, -1, 0; ; p++ {
+		j = rl.run(i)
+		if i == rl.starts[j] {
+			starts[j] = p
+		}
+		if i == rl.end(j) {
+			x.ends[j] = p
+		}
+		if _, ok := bucket[i]; ok {
+			bucket[i] = p
+		}
+
+		c := bwt[i]
+		if c == 0 {
+			break
+		} else if c == separator {
+			x.offsets = append(x.offsets, p+2)
+			i = sep
+			sep++
+		} else {
+			i = f.lf(c, i)
+		}
+	}
+
+	// the row before LF(i) for the first row i of a run of c is either the last row of the previous
+	// run of c mapped by LF, or the row before the start of bucket c
+	type entry struct{ key, val int }
+	var phi []entry
+	for c := separator + 1; c < alphabetSize; c++ {
+		for m, j := range rl.runs[c] {
+			if m == 0 {
+				phi = append(phi, entry{starts[j] + 1, bucket[f.c[f.code[c]]-1]})
+			} else {
+				phi = append(phi, entry{starts[j] + 1, x.ends[rl.runs[c][m-1]] + 1})
+			}
+		}
+	}
+
+	// separators are sorted by their positions, the row before a separator is the previous separator,
+	// or sentinel for the first separator
+	for k, s := range x.offsets[1:] {
+		phi = append(phi, entry{s - 1, x.offsets[k] - 1})
+	}
+
+	// note: the first row is sentinel, its key is 0, every position has a key
+	sort.Slice(phi, func(i, j int) bool { return phi[i].key < phi[j].key })
+	x.keys, x.vals = make([]int, len(phi)), make([]int, len(phi))
+	for k, e := range phi {
+		x.keys[k], x.vals[k] = e.key, e.val
+	}
+
+	return x
+}
+
+// Runs returns the number of runs of BWT
+func (x *RIndex) Runs() int {
+	return x.rl.Runs()
+}
+
+// Len returns the length of the text
+func (x *RIndex) Len() int {
+	return x.f.Len()
+}
+
+// Count returns the number of occurrences of pattern
+func (x *RIndex) Count(pattern []byte) int {
+	return x.f.Count(x.translate(pattern))
+}
+
+// translate returns pattern of the bytes of Aux
+func (x *RIndex) translate(pattern []byte) []byte {
+	if x.code == nil {
+		return pattern
+	}
+
+	p := append([]byte(nil), pattern...)
+	translate(p, x.code)
+	return p
+}
+
+// Locate returns the start positions of pattern in ascending order
+func (x *RIndex) Locate(pattern []byte) []int {
+	if len(pattern) == 0 {
+		return nil
+	}
+
+	// toehold, v is the suffix array value of row hi - 1
+	f, rl, pattern := x.f, x.rl, x.translate(pattern)
+	lo, hi, v := 0, rl.Len(), x.ends[rl.Runs()-1]
+	for _, c := range pattern {
+		k := f.code[c]
+		if c <= separator || k < 0 {
+			return []int{}
+		}
+
+		n := rl.Rank(c, hi)
+		nlo, nhi := f.c[k]+rl.Rank(c, lo), f.c[k]+n
+		if nlo >= nhi {
+			return []int{}
+		}
+
+		if rl.Access(hi-1) == c {
+			v++
+		} else {
+			// the last c in [lo, hi) is the end of a run of c
+			m := sort.Search(len(rl.runs[c]), func(m int) bool { return rl.cum[c][m+1] >= n })
+			v = x.ends[rl.runs[c][m]] + 1
+		}
+		lo, hi = nlo, nhi
+	}
+
+	pos := make([]int, hi-lo)
+	for i := range pos {
+		pos[i] = v - len(pattern) + 1
+		v = x.phi(v)
+	}
+	sort.Ints(pos)
+
+	return pos
+}
+
+// LocateDocs returns the positions of pattern as string and offset in ascending order, see Locate
+func (x *RIndex) LocateDocs(pattern []byte) []Match {
+	return toMatches(x.Locate(pattern), len(x.offsets), func(d int) int { return x.offsets[d] })
+}
+
+// phi returns suffix array value of the row before the row of suffix array value v
+func (x *RIndex) phi(v int) int {
+	k := sort.Search(len(x.keys), func(k int) bool { return x.keys[k] > v }) - 1
+	return x.vals[k] + v - x.keys[k]
+}
@@ -0,0 +1,102 @@
+package sa
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// repetitiveText generates copies of a random string with a few mutations
+func repetitiveText(r *rand.Rand, n, k int) []byte {
+	base := randText(r, 1+r.Intn(50), k, false)
+	var c Collection
+	for len(c.Text()) < n {
+		doc := append([]byte{}, base...)
+		if r.Intn(10) == 0 {
+			doc[r.Intn(len(doc))] = byte('a' + r.Intn(k))
+		}
+		c.Add(doc)
+	}
+
+	return c.Text()
+}
+
+func TestRLBWT(t *testing.T) {
+	_, bwt, _ := BWT(toByte("sisisim$sisisim$anana", '$', 1))
+	rl := NewRLBWT(bwt)
+	for i, c := range bwt {
+		if got := rl.Access(i); got != c {
+			t.Fatalf("Access(%d) = %d, want %d", i, got, c)
+		}
+		for _, c := range []byte{0, 1, 'a', 'i', 'm', 'n', 's', 'x'} {
+			if got, want := rl.Rank(c, i), countBefore(bwt, c, i); got != want {
+				t.Fatalf("Rank(%c, %d) = %d, want %d", c, i, got, want)
+			}
+		}
+	}
+}
+
+func countBefore(bwt []byte, c byte, i int) int {
+	n := 0
+	for _, x := range bwt[:i] {
+		if x == c {
+			n++
+		}
+	}
+
+	return n
+}
+
+func TestRIndex(t *testing.T) {
+	r := rand.New(rand.NewSource(12))
+	for i := 0; i < 300; i++ {
+		var text []byte
+		if i%2 == 0 {
+			text = repetitiveText(r, 2+r.Intn(800), 1+r.Intn(4))
+		} else {
+			text = randText(r, 2+r.Intn(300), 1+r.Intn(4), i%3 == 0)
+		}
+		_, bwt, aux := BWT(append([]byte{}, text...))
+		f := NewFMIndex(bwt, aux)
+		f.Sample(1)
+		x := NewRIndex(bwt, aux)
+
+		if x.Len() != len(text) {
+			t.Fatalf("Len() = %d, want %d", x.Len(), len(text))
+		}
+		for j := 0; j < 20; j++ {
+			p := randText(r, 1+r.Intn(6), 1+r.Intn(4), false)
+			if got, want := x.Count(p), f.Count(p); got != want {
+				t.Fatalf("Count(%q) = %d, want %d", p, got, want)
+			}
+			if got, want := x.Locate(p), f.Locate(p); !reflect.DeepEqual(got, want) {
+				t.Fatalf("Locate(%q) in %q = %v, want %v", p, toString(append([]byte{}, text...), 1, '$'), got, want)
+			}
+			if got, want := x.LocateDocs(p), f.LocateDocs(p); !reflect.DeepEqual(got, want) {
+				t.Fatalf("LocateDocs(%q) = %v, want %v", p, got, want)
+			}
+		}
+	}
+}
+
+func TestRIndexRuns(t *testing.T) {
+	r := rand.New(rand.NewSource(13))
+	base := randText(r, 1000, 4, false)
+	var c Collection
+	for i := 0; i < 100; i++ {
+		doc := append([]byte{}, base...)
+		if i%20 == 0 {
+			doc[r.Intn(len(doc))] = 'e'
+		}
+		c.Add(doc)
+	}
+
+	_, bwt, aux := BWT(append([]byte{}, c.Text()...))
+	x := NewRIndex(bwt, aux)
+	if x.Runs() > len(bwt)/20 {
+		t.Errorf("Runs() = %d, want less than %d", x.Runs(), len(bwt)/20)
+	}
+	if got := x.Count(base[100:120]); got < 95 {
+		t.Errorf("Count() = %d, want at least 95", got)
+	}
+}
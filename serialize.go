@@ -0,0 +1,267 @@
+/*
+ * Copyright 2020 Rock Lei Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sa
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"hash/crc32"
+	"io"
+)
+
+const (
+	// indexVersion version of the on disk format of Index
+	indexVersion = 2
+
+	// chunk size of reading slices, corrupted lengths fail at EOF instead of allocating
+	readChunk = 1 << 20
+)
+
+var (
+	indexMagic = []byte("SAIX")
+
+	// ErrFormat data is not a serialized index
+	ErrFormat = errors.New("sa: invalid index format")
+
+	// ErrVersion index is written by an unsupported version
+	ErrVersion = errors.New("sa: unsupported index version")
+
+	// ErrChecksum checksum of index does not match
+	ErrChecksum = errors.New("sa: index checksum mismatch")
+)
+
+// Index output of BWT, which can be persisted by WriteTo, restored by ReadFrom, and merged by MergeBWT
+//
+// On disk format, integers are little endian uint64:
+// magic "SAIX", uint32 version, L, BWT length and bytes, Aux.Len, number of Aux.Eob rows, each row
+// length and values, Aux.Dist length and values, Aux.Hist length and values, Aux.Dict length and
+// bytes, Aux.Binary as 0 or 1, Aux.End, Aux.Separator and Aux.Sentinel bytes, followed by uint32 crc32
+// (Castagnoli) of all the preceding bytes
+type Index struct {
+	// L row of the end of text in BWT
+	L int
+
+	// BWT of the text, see BWT
+	BWT []byte
+
+	// Aux auxiliary data structure of BWT
+	Aux *Aux
+}
+
+// Text restores the text of x by the mode recorded in Aux, see Options.InverseBWT
+func (x *Index) Text() []byte {
+	o := &Options{}
+	if x.Aux != nil {
+		o.Binary, o.Separator, o.Sentinel = x.Aux.Binary, x.Aux.Separator, x.Aux.Sentinel
+	}
+	return o.InverseBWT(x.BWT, x.L)
+}
+
+// WriteTo writes x to w, implements io.WriterTo
+func (x *Index) WriteTo(w io.Writer) (int64, error) {
+	e := &encoder{w: bufio.NewWriter(w), h: crc32.New(crc32.MakeTable(crc32.Castagnoli))}
+	e.bytes(indexMagic)
+	var ver [4]byte
+	binary.LittleEndian.PutUint32(ver[:], indexVersion)
+	e.bytes(ver[:])
+
+	e.uint(uint64(x.L))
+	e.uint(uint64(len(x.BWT)))
+	e.bytes(x.BWT)
+
+	aux := x.Aux
+	e.uint(uint64(aux.Len))
+	e.uint(uint64(len(aux.Eob)))
+	for _, row := range aux.Eob {
+		e.uints(row)
+	}
+	e.uints(aux.Dist)
+	e.uints(aux.Hist)
+	e.uint(uint64(len(aux.Dict)))
+	e.bytes(aux.Dict)
+	if aux.Binary {
+		e.uint(1)
+	} else {
+		e.uint(0)
+	}
+	e.uint(uint64(aux.End))
+	e.bytes([]byte{aux.Separator, aux.Sentinel})
+
+	// note: checksum is not part of itself
+	var sum [4]byte
+	binary.LittleEndian.PutUint32(sum[:], e.h.Sum32())
+	e.h = nil
+	e.bytes(sum[:])
+
+	if e.err == nil {
+		e.err = e.w.Flush()
+	}
+	return e.n, e.err
+}
+
+// ReadFrom reads x from r written by WriteTo, implements io.ReaderFrom. r is not read past the end of
+// the index, wrap r in bufio.Reader for small reads of an unbuffered file.
+// Returns ErrFormat, ErrVersion or ErrChecksum if data is invalid, io.ErrUnexpectedEOF if truncated.
+func (x *Index) ReadFrom(r io.Reader) (int64, error) {
+	d := &decoder{r: r, h: crc32.New(crc32.MakeTable(crc32.Castagnoli))}
+	var hdr [8]byte
+	d.read(hdr[:])
+	if d.err == nil && !bytes.Equal(hdr[:4], indexMagic) {
+		return d.n, ErrFormat
+	} else if d.err == nil && binary.LittleEndian.Uint32(hdr[4:]) != indexVersion {
+		return d.n, ErrVersion
+	}
+
+	l := d.uint()
+	bwt := d.bytes(d.uint())
+
+	aux := &Aux{Len: uint(d.uint())}
+	if rows := d.uint(); d.err == nil && rows > alphabetSize {
+		d.err = ErrFormat
+	} else {
+		aux.Eob = make([][]uint, rows)
+	}
+	for i := range aux.Eob {
+		aux.Eob[i] = d.uints(d.uint())
+	}
+	aux.Dist = d.uints(d.uint())
+	aux.Hist = d.uints(d.uint())
+	aux.Dict = d.bytes(d.uint())
+	bin, end := d.uint(), d.uint()
+	aux.Binary, aux.End = bin == 1, uint(end)
+	var code [2]byte
+	d.read(code[:])
+	aux.Separator, aux.Sentinel = code[0], code[1]
+
+	sum := d.h.Sum32()
+	d.h = nil
+	var stored [4]byte
+	d.read(stored[:])
+	if d.err != nil {
+		return d.n, d.err
+	} else if binary.LittleEndian.Uint32(stored[:]) != sum {
+		return d.n, ErrChecksum
+	} else if l >= uint64(len(bwt)) || aux.Len != uint(len(bwt)) || bin > 1 || aux.Binary && end != l ||
+		code[0] == code[1] && code[0] != 0 || code[0] == 0 && code[1] != 0 {
+		return d.n, ErrFormat
+	}
+
+	x.L, x.BWT, x.Aux = int(l), bwt, aux
+	return d.n, nil
+}
+
+// encoder writes and hashes, stops at the first error
+type encoder struct {
+	w   *bufio.Writer
+	h   hash.Hash32
+	n   int64
+	err error
+}
+
+func (e *encoder) bytes(p []byte) {
+	if e.err != nil {
+		return
+	}
+	if e.h != nil {
+		e.h.Write(p)
+	}
+	k, err := e.w.Write(p)
+	e.n, e.err = e.n+int64(k), err
+}
+
+func (e *encoder) uint(v uint64) {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], v)
+	e.bytes(b[:])
+}
+
+func (e *encoder) uints(s []uint) {
+	e.uint(uint64(len(s)))
+	for _, v := range s {
+		e.uint(uint64(v))
+	}
+}
+
+// decoder reads exactly the bytes of index and hashes, stops at the first error
+type decoder struct {
+	r   io.Reader
+	h   hash.Hash32
+	n   int64
+	err error
+}
+
+func (d *decoder) read(p []byte) {
+	if d.err != nil {
+		return
+	}
+	k, err := io.ReadFull(d.r, p)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	d.n, d.err = d.n+int64(k), err
+	if d.h != nil {
+		d.h.Write(p[:k])
+	}
+}
+
+func (d *decoder) uint() uint64 {
+	var b [8]byte
+	d.read(b[:])
+	return binary.LittleEndian.Uint64(b[:])
+}
+
+// bytes reads n bytes by chunks
+func (d *decoder) bytes(n uint64) []byte {
+	var p []byte
+	for n > 0 && d.err == nil {
+		k := n
+		if k > readChunk {
+			k = readChunk
+		}
+		p = append(p, make([]byte, k)...)
+		d.read(p[uint64(len(p))-k:])
+		n -= k
+	}
+	if p == nil {
+		p = []byte{}
+	}
+	return p
+}
+
+// uints reads n uint64 values by chunks
+func (d *decoder) uints(n uint64) []uint {
+	s, b := make([]uint, 0, minUint64(n, readChunk)), make([]byte, 8*minUint64(n, readChunk/8))
+	for n > 0 && d.err == nil {
+		k := minUint64(n, readChunk/8)
+		d.read(b[:8*k])
+		for i := uint64(0); i < k && d.err == nil; i++ {
+			s = append(s, uint(binary.LittleEndian.Uint64(b[8*i:])))
+		}
+		n -= k
+	}
+	return s
+}
+
+func minUint64(x, y uint64) uint64 {
+	if x < y {
+		return x
+	}
+	return y
+}
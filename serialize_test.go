@@ -0,0 +1,80 @@
+package sa
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestIndexSerialize(t *testing.T) {
+	r := rand.New(rand.NewSource(14))
+	for i := 0; i < 50; i++ {
+		text := randText(r, 2+r.Intn(2000), 1+r.Intn(30), i%2 == 0)
+		l, bwt, aux := BWT(append([]byte{}, text...))
+		x := &Index{l, bwt, aux}
+
+		var buf bytes.Buffer
+		n, err := x.WriteTo(&buf)
+		if err != nil || n != int64(buf.Len()) {
+			t.Fatalf("WriteTo() = %d, %v, wrote %d bytes", n, err, buf.Len())
+		}
+		data := buf.Bytes()
+
+		var y Index
+		if n, err := y.ReadFrom(bytes.NewReader(data)); err != nil || n != int64(len(data)) {
+			t.Fatalf("ReadFrom() = %d, %v, want %d", n, err, len(data))
+		}
+		if !reflect.DeepEqual(&y, x) {
+			t.Fatalf("ReadFrom() = %+v, want %+v", y, x)
+		}
+
+		// index embedded in a stream, bytes after the index are not consumed
+		rd := bytes.NewReader(append(append([]byte{}, data...), "tail"...))
+		if n, err := new(Index).ReadFrom(rd); err != nil || n != int64(len(data)) || rd.Len() != 4 {
+			t.Fatalf("ReadFrom() = %d, %v, %d bytes left, want %d, 4 bytes left", n, err, rd.Len(), len(data))
+		}
+		if got := InverseBWT(y.BWT, y.L); !bytes.Equal(got, text) {
+			t.Fatalf("InverseBWT() = %q, want %q", got, text)
+		}
+
+		// errors
+		bad := append([]byte{}, data...)
+		bad[8+r.Intn(len(bad)-8)] ^= 0x10
+		if _, err := new(Index).ReadFrom(bytes.NewReader(bad)); err == nil {
+			t.Fatalf("ReadFrom() of corrupted data succeeded")
+		}
+		if _, err := new(Index).ReadFrom(bytes.NewReader(data[:r.Intn(len(data))])); err != io.ErrUnexpectedEOF {
+			t.Fatalf("ReadFrom() of truncated data = %v, want %v", err, io.ErrUnexpectedEOF)
+		}
+	}
+
+	var x Index
+	if _, err := x.ReadFrom(bytes.NewReader([]byte("SAIY\x01\x00\x00\x00"))); err != ErrFormat {
+		t.Fatalf("ReadFrom() = %v, want %v", err, ErrFormat)
+	}
+	if _, err := x.ReadFrom(bytes.NewReader([]byte("SAIX\x01\x00\x00\x00"))); err != ErrVersion {
+		t.Fatalf("ReadFrom() = %v, want %v", err, ErrVersion)
+	}
+}
+
+func TestIndexMerge(t *testing.T) {
+	a, b := []byte("sisisim\x01anana"), []byte("mississippi")
+	want := append(append(append([]byte{}, a...), separator), b...)
+
+	var bufs [2]bytes.Buffer
+	for k, text := range [][]byte{a, b} {
+		l, bwt, aux := BWT(append([]byte{}, text...))
+		(&Index{l, bwt, aux}).WriteTo(&bufs[k])
+	}
+
+	var x, y Index
+	x.ReadFrom(&bufs[0])
+	y.ReadFrom(&bufs[1])
+	bwt, _ := MergeBWT(x.BWT, y.BWT, x.Aux, y.Aux)
+	_, expect, _ := BWT(want)
+	if !bytes.Equal(bwt, expect) {
+		t.Fatalf("MergeBWT() of reloaded indexes = %v, want %v", bwt, expect)
+	}
+}
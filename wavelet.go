@@ -0,0 +1,178 @@
+/*
+ * Copyright 2020 Rock Lei Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sa
+
+import "math/bits"
+
+// WaveletMatrix rank and select over the bytes of BWT, one bit vector per bit of the index in Aux.Dict
+type WaveletMatrix struct {
+	n int
+
+	// dict of the bytes of BWT from Aux, code[c] is the index of byte c in dict, -1 if c is not in BWT
+	dict []byte
+	code [alphabetSize]int
+
+	// levels[l] bits of level l, from the most significant bit of the index in dict
+	levels []*bitvec
+
+	// zeros[l] number of zeros at level l
+	zeros []int
+}
+
+// NewWaveletMatrix creates WaveletMatrix of bwt, bwt and aux are returned by BWT
+func NewWaveletMatrix(bwt []byte, aux *Aux) *WaveletMatrix {
+	w := &WaveletMatrix{n: len(bwt), dict: aux.symbols()}
+	for i := range w.code {
+		w.code[i] = -1
+	}
+	for k, c := range w.dict {
+		w.code[c] = k
+	}
+
+	depth := bits.Len(uint(len(w.dict) - 1))
+	if depth == 0 {
+		depth = 1
+	}
+
+	// stable sort indexes by bit of each level, zeros before ones
+	cur, next := make([]byte, len(bwt)), make([]byte, len(bwt))
+	for i, c := range bwt {
+		cur[i] = byte(w.code[c])
+	}
+	for l := 0; l < depth; l++ {
+		shift := uint(depth - 1 - l)
+		bv, z := newBitvec(len(bwt)), 0
+		for i, k := range cur {
+			if k>>shift&1 == 0 {
+				z++
+			} else {
+				bv.set(i)
+			}
+		}
+		bv.build()
+		w.levels = append(w.levels, bv)
+		w.zeros = append(w.zeros, z)
+
+		zi, oi := 0, z
+		for _, k := range cur {
+			if k>>shift&1 == 0 {
+				next[zi], zi = k, zi+1
+			} else {
+				next[oi], oi = k, oi+1
+			}
+		}
+		cur, next = next, cur
+	}
+
+	return w
+}
+
+// Len returns the number of rows
+func (w *WaveletMatrix) Len() int {
+	return w.n
+}
+
+// Access returns the byte of row i
+func (w *WaveletMatrix) Access(i int) byte {
+	k := 0
+	for l, bv := range w.levels {
+		if bv.get(i) {
+			k, i = k<<1|1, w.zeros[l]+bv.rank1(i)
+		} else {
+			k, i = k<<1, bv.rank0(i)
+		}
+	}
+
+	return w.dict[k]
+}
+
+// Rank returns the number of byte c before row i
+func (w *WaveletMatrix) Rank(c byte, i int) int {
+	k := w.code[c]
+	if k < 0 {
+		return 0
+	}
+
+	// p is the start of byte c at the bottom level
+	p := 0
+	for l, bv := range w.levels {
+		if w.bit(k, l) == 0 {
+			p, i = bv.rank0(p), bv.rank0(i)
+		} else {
+			p, i = w.zeros[l]+bv.rank1(p), w.zeros[l]+bv.rank1(i)
+		}
+	}
+
+	return i - p
+}
+
+// Select returns the row of the k-th byte c, k starts from 0, -1 if there are no more than k byte c
+func (w *WaveletMatrix) Select(c byte, k int) int {
+	if k < 0 || k >= w.Rank(c, w.n) {
+		return -1
+	}
+
+	x, p := w.code[c], 0
+	for l, bv := range w.levels {
+		if w.bit(x, l) == 0 {
+			p = bv.rank0(p)
+		} else {
+			p = w.zeros[l] + bv.rank1(p)
+		}
+	}
+
+	// walk up from the bottom level
+	p += k
+	for l := len(w.levels) - 1; l >= 0; l-- {
+		if w.bit(x, l) == 0 {
+			p = w.levels[l].select0(p)
+		} else {
+			p = w.levels[l].select1(p - w.zeros[l])
+		}
+	}
+
+	return p
+}
+
+// Distinct returns distinct bytes in rows [lo, hi) in ascending order and their number of occurrences
+func (w *WaveletMatrix) Distinct(lo, hi int) ([]byte, []int) {
+	var syms []byte
+	var cnts []int
+	var walk func(l, lo, hi, k int)
+	walk = func(l, lo, hi, k int) {
+		if lo >= hi {
+			return
+		}
+		if l == len(w.levels) {
+			syms, cnts = append(syms, w.dict[k]), append(cnts, hi-lo)
+			return
+		}
+
+		bv := w.levels[l]
+		zlo, zhi := bv.rank0(lo), bv.rank0(hi)
+		walk(l+1, zlo, zhi, k<<1)
+		walk(l+1, w.zeros[l]+lo-zlo, w.zeros[l]+hi-zhi, k<<1|1)
+	}
+	walk(0, lo, hi, 0)
+
+	return syms, cnts
+}
+
+// bit returns the bit of level l of index k
+func (w *WaveletMatrix) bit(k, l int) int {
+	return k >> uint(len(w.levels)-1-l) & 1
+}
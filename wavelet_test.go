@@ -0,0 +1,97 @@
+package sa
+
+import (
+	"bytes"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestWaveletMatrix(t *testing.T) {
+	r := rand.New(rand.NewSource(10))
+	for i := 0; i < 100; i++ {
+		text := randText(r, 2+r.Intn(2000), 1+r.Intn(30), i%2 == 0)
+		_, bwt, aux := BWT(append([]byte{}, text...))
+		w := NewWaveletMatrix(bwt, aux)
+
+		if w.Len() != len(bwt) {
+			t.Fatalf("Len() = %d, want %d", w.Len(), len(bwt))
+		}
+		for j, c := range bwt {
+			if got := w.Access(j); got != c {
+				t.Fatalf("Access(%d) = %d, want %d", j, got, c)
+			}
+		}
+		for j := 0; j < 50; j++ {
+			c, k := byte('a'+r.Intn(32)), r.Intn(len(bwt)+1)
+			if j%10 == 0 {
+				c = byte(r.Intn(2))
+			}
+			if got, want := w.Rank(c, k), bytes.Count(bwt[:k], []byte{c}); got != want {
+				t.Fatalf("Rank(%d, %d) = %d, want %d", c, k, got, want)
+			}
+
+			want := -1
+			for p, n := 0, 0; p < len(bwt); p++ {
+				if bwt[p] == c {
+					if n == k%20 {
+						want = p
+						break
+					}
+					n++
+				}
+			}
+			if got := w.Select(c, k%20); got != want {
+				t.Fatalf("Select(%d, %d) = %d, want %d", c, k%20, got, want)
+			}
+
+			lo, hi := r.Intn(len(bwt)), r.Intn(len(bwt)+1)
+			cnt := map[byte]int{}
+			for _, c := range bwt[lo:maxInt(lo, hi)] {
+				cnt[c]++
+			}
+			syms, cnts := w.Distinct(lo, hi)
+			got := map[byte]int{}
+			for x, c := range syms {
+				if x > 0 && syms[x-1] >= c {
+					t.Fatalf("Distinct(%d, %d) = %v, not ascending", lo, hi, syms)
+				}
+				got[c] = cnts[x]
+			}
+			if !reflect.DeepEqual(got, cnt) {
+				t.Fatalf("Distinct(%d, %d) = %v, want %v", lo, hi, got, cnt)
+			}
+		}
+	}
+}
+
+func TestFMIndexRanker(t *testing.T) {
+	r := rand.New(rand.NewSource(11))
+	for i := 0; i < 100; i++ {
+		text := randText(r, 2+r.Intn(600), 1+r.Intn(4), i%2 == 0)
+		_, bwt, aux := BWT(append([]byte{}, text...))
+		f, w := NewFMIndex(bwt, aux), NewFMIndexRanker(NewWaveletMatrix(bwt, aux), aux)
+		f.Sample(4)
+		w.Sample(4)
+
+		if got := w.Text(); !reflect.DeepEqual(got, text) {
+			t.Fatalf("Text() = %q, want %q", toString(got, 1, '$'), toString(text, 1, '$'))
+		}
+		for j := 0; j < 20; j++ {
+			p := randText(r, 1+r.Intn(4), 1+r.Intn(4), false)
+			if got, want := w.Count(p), f.Count(p); got != want {
+				t.Fatalf("Count(%q) = %d, want %d", p, got, want)
+			}
+			if got, want := w.Locate(p), f.Locate(p); !reflect.DeepEqual(got, want) {
+				t.Fatalf("Locate(%q) = %v, want %v", p, got, want)
+			}
+		}
+	}
+}
+
+func maxInt(x, y int) int {
+	if x > y {
+		return x
+	}
+	return y
+}